@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"cix/pkg/httpcache"
+)
+
+func runCache(args []string) error {
+	fs := flag.NewFlagSet("cache", flag.ExitOnError)
+	dir := fs.String("cache-dir", httpcache.DefaultDir(), "directory HTTP responses are cached in")
+	ttl := fs.Duration("cache-ttl", time.Hour, "how long Prow history pages are served from the cache")
+	expiredOnly := fs.Bool("expired", false, "purge only history pages older than -cache-ttl")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: prstats cache [flags] inspect|purge")
+	}
+	cache := &httpcache.Cache{Dir: *dir, TTL: *ttl}
+
+	switch fs.Arg(0) {
+	case "inspect":
+		return inspectCache(cache)
+	case "purge":
+		return purgeCache(cache, *expiredOnly)
+	}
+	return fmt.Errorf("unknown cache action %q, expected inspect or purge", fs.Arg(0))
+}
+
+func inspectCache(cache *httpcache.Cache) error {
+	type kindTotals struct {
+		entries, expired int
+		bytes            int64
+	}
+	totals := make(map[httpcache.Kind]*kindTotals)
+	var oldest time.Time
+	err := cache.Walk(func(path string, entry *httpcache.Entry) error {
+		t := totals[entry.Kind]
+		if t == nil {
+			t = &kindTotals{}
+			totals[entry.Kind] = t
+		}
+		t.entries++
+		if cache.Expired(entry) {
+			t.expired++
+		}
+		if info, err := os.Stat(path); err == nil {
+			t.bytes += info.Size()
+		}
+		if oldest.IsZero() || entry.StoredAt.Before(oldest) {
+			oldest = entry.StoredAt
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read cache: %v", err)
+	}
+
+	kinds := make([]string, 0, len(totals))
+	for kind := range totals {
+		kinds = append(kinds, string(kind))
+	}
+	sort.Strings(kinds)
+
+	fmt.Printf("Cache %s:\n", cache.Dir)
+	for _, kind := range kinds {
+		t := totals[httpcache.Kind(kind)]
+		fmt.Printf("\t%s: %d entries, %d expired, %.1f MB\n", kind, t.entries, t.expired, float64(t.bytes)/(1<<20))
+	}
+	if !oldest.IsZero() {
+		fmt.Printf("\toldest entry stored %s\n", oldest.Format(time.RFC3339))
+	}
+	return nil
+}
+
+func purgeCache(cache *httpcache.Cache, expiredOnly bool) error {
+	removed := 0
+	err := cache.Walk(func(path string, entry *httpcache.Entry) error {
+		if expiredOnly && !cache.Expired(entry) {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		removed++
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to purge cache: %v", err)
+	}
+	fmt.Printf("Removed %d entries from %s\n", removed, cache.Dir)
+	return nil
+}
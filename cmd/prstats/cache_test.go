@@ -0,0 +1,48 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"cix/pkg/httpcache"
+)
+
+func TestPurgeCache(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "{}")
+	}))
+	defer server.Close()
+	cache, err := httpcache.New(t.TempDir(), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/pr-history/", "/logs/job/1/finished.json"} {
+		resp, err := cache.Client().Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	entries := func() int {
+		n := 0
+		if err := cache.Walk(func(string, *httpcache.Entry) error { n++; return nil }); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	// nothing has expired yet
+	if err := purgeCache(cache, true); err != nil || entries() != 2 {
+		t.Errorf("purge of expired entries left %d entries (%v), want 2", entries(), err)
+	}
+	cache.TTL = time.Nanosecond
+	if err := purgeCache(cache, true); err != nil || entries() != 1 {
+		t.Errorf("purge of expired entries left %d entries (%v), want 1", entries(), err)
+	}
+	if err := purgeCache(cache, false); err != nil || entries() != 0 {
+		t.Errorf("purge left %d entries (%v), want none", entries(), err)
+	}
+}
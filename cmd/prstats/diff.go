@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"cix/pkg/cost"
+	"cix/pkg/report"
+)
+
+// exitDiffer is the exit status of a diff finding differences beyond the
+// tolerance, so CI can tell a regression from a failed or misused diff.
+const exitDiffer = 3
+
+func runDiff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	format := fs.String("format", "text", "output format: text or json")
+	var tolerance cost.Tolerance
+	fs.Float64Var(&tolerance.Hours, "tolerance-hours", 0.01, "billable hours a run may move by before it is reported")
+	fs.Float64Var(&tolerance.Cost, "tolerance-cost", 0.01, "dollars a run or PR may move by before it is reported")
+	fs.Float64Var(&tolerance.Percent, "tolerance-pct", 0, "percent of the old value a run or PR may also move by before it is reported")
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		return fmt.Errorf("usage: prstats diff [flags] <old.json> <new.json>")
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown -format %q", *format)
+	}
+
+	oldReport, err := cost.ReadReport(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	newReport, err := cost.ReadReport(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	diff := cost.DiffReports(oldReport, newReport, tolerance)
+	diff.Old, diff.New = fs.Arg(0), fs.Arg(1)
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(diff); err != nil {
+			return fmt.Errorf("failed to write diff: %v", err)
+		}
+	} else {
+		report.PrintDiff(os.Stdout, diff)
+	}
+
+	if !diff.Empty() {
+		return &exitError{code: exitDiffer, err: fmt.Errorf("reports differ: %d PRs added, %d removed, %d changed",
+			len(diff.AddedPRs), len(diff.RemovedPRs), len(diff.Changed))}
+	}
+	return nil
+}
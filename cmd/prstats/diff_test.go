@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"cix/pkg/cost"
+)
+
+func writeReportFile(t *testing.T, name string, prCost float64) string {
+	t.Helper()
+	data, err := json.Marshal(&cost.Report{PRs: []cost.PRInfo{{Org: "openshift", Repo: "ovn-kubernetes", PRNum: 1, TotalCost: prCost}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDiffExitStatus(t *testing.T) {
+	old := writeReportFile(t, "old.json", 5)
+	if err := runDiff([]string{old, writeReportFile(t, "same.json", 5)}); err != nil {
+		t.Errorf("diff of matching reports failed: %v", err)
+	}
+
+	err := runDiff([]string{old, writeReportFile(t, "new.json", 0)})
+	var exit *exitError
+	if !errors.As(err, &exit) || exit.code != exitDiffer {
+		t.Errorf("diff of differing reports returned %v, want exit status %d", err, exitDiffer)
+	}
+
+	// usage errors keep the usual status
+	err = runDiff([]string{old})
+	if err == nil || errors.As(err, &exit) {
+		t.Errorf("diff with one report returned %v, want a plain error", err)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"cix/pkg/report"
+)
+
+// formatsFlag collects the report formats given to -format, which may be
+// repeated or hold a comma-separated list.
+type formatsFlag []string
+
+func (f *formatsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *formatsFlag) Set(value string) error {
+	for _, spec := range strings.Split(value, ",") {
+		if spec = strings.TrimSpace(spec); spec != "" {
+			*f = append(*f, spec)
+		}
+	}
+	return nil
+}
+
+func addFormatFlag(fs *flag.FlagSet) *formatsFlag {
+	f := &formatsFlag{}
+	fs.Var(f, "format", "report format to write besides JSON: "+strings.Join(report.Formats(), ", ")+
+		"; name=path writes it to path, - for stdout, else it is written next to the JSON report (repeatable)")
+	return f
+}
+
+// check fails when the formats cannot be written: a format given a path can
+// only be written when the command writes a single report.
+func (f *formatsFlag) check(single bool) error {
+	if _, err := report.ParseOutputs(*f, "report.json"); err != nil {
+		return err
+	}
+	if single {
+		return nil
+	}
+	for _, spec := range *f {
+		if _, path, _ := strings.Cut(spec, "="); path != "" && path != "-" {
+			return fmt.Errorf("-format %s: a path can only be given when a single report is written", spec)
+		}
+	}
+	return nil
+}
+
+// text returns where the text report of a command whose JSON goes to base is
+// printed: stdout, or stderr when a report format is written to stdout, so
+// the text does not end up inside it.
+func (f *formatsFlag) text(base string) io.Writer {
+	outputs, err := f.outputs(base)
+	if err != nil {
+		// reported by check or outputs
+		return os.Stdout
+	}
+	for _, o := range outputs {
+		if o.Path == "-" {
+			return os.Stderr
+		}
+	}
+	return os.Stdout
+}
+
+// outputs returns the outputs of a report whose JSON goes to base.
+func (f *formatsFlag) outputs(base string) ([]report.Output, error) {
+	return report.ParseOutputs(*f, base)
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"cix/pkg/analysis"
+	"cix/pkg/cassette"
+	"cix/pkg/httpcache"
+	"cix/pkg/report"
+	"cix/pkg/transport"
+)
+
+// httpFlags choose how the GitHub and Prow clients reach the network:
+// through the response cache, recording into a cassette, or replaying one
+// without a network, how many requests each host gets and how long they may
+// take.
+type httpFlags struct {
+	cacheDir *string
+	cacheTTL *time.Duration
+	noCache  *bool
+	record   *string
+	replay   *string
+	// per-request and whole-run deadlines
+	requestTimeout *time.Duration
+	timeout        *time.Duration
+	// concurrency and rate of requests per host, and retries of server
+	// errors and timeouts
+	hostConcurrency *int
+	hostLimits      hostLimits
+	retries         *int
+
+	cache     *httpcache.Cache
+	cassette  *cassette.Cassette
+	transport *transport.Transport
+}
+
+// hostLimits collects -host-limit flags, each host=concurrency[:rate].
+type hostLimits map[string]transport.HostLimit
+
+func (h hostLimits) String() string {
+	var limits []string
+	for host, limit := range h {
+		limits = append(limits, fmt.Sprintf("%s=%d:%g", host, limit.Concurrency, limit.Rate))
+	}
+	sort.Strings(limits)
+	return strings.Join(limits, ",")
+}
+
+func (h hostLimits) Set(value string) error {
+	host, limit, ok := strings.Cut(value, "=")
+	if !ok || host == "" {
+		return fmt.Errorf("expected host=concurrency[:rate], got %q", value)
+	}
+	parsed, err := transport.ParseHostLimit(limit)
+	if err != nil {
+		return err
+	}
+	h[host] = parsed
+	return nil
+}
+
+func addHTTPFlags(fs *flag.FlagSet) *httpFlags {
+	f := &httpFlags{
+		cacheDir:        fs.String("cache-dir", httpcache.DefaultDir(), "directory HTTP responses are cached in"),
+		cacheTTL:        fs.Duration("cache-ttl", time.Hour, "how long Prow history pages are served from the cache"),
+		noCache:         fs.Bool("no-cache", false, "send every request to the server without caching responses"),
+		record:          fs.String("record", "", "cassette directory to record every HTTP exchange into (bypasses the cache)"),
+		replay:          fs.String("replay", "", "cassette directory to replay HTTP exchanges from instead of using the network"),
+		requestTimeout:  fs.Duration("request-timeout", 2*time.Minute, "how long a single HTTP request may take"),
+		timeout:         fs.Duration("timeout", 0, "how long the whole run may take, no limit when 0"),
+		hostConcurrency: fs.Int("host-concurrency", 10, "requests in flight at once per host without a -host-limit"),
+		hostLimits:      make(hostLimits),
+		retries:         fs.Int("retries", 3, "retries, with exponential backoff, of requests failing with a server error or timeout"),
+	}
+	fs.Var(f.hostLimits, "host-limit", "host=concurrency[:rate] limits the requests in flight and started per second for a host, repeatable")
+	return f
+}
+
+// context returns the context the run is done in. It is cancelled on SIGINT
+// or SIGTERM and once -timeout has passed.
+func (f *httpFlags) context() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	if *f.timeout <= 0 {
+		return ctx, stop
+	}
+	ctx, cancel := context.WithTimeout(ctx, *f.timeout)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
+// client returns the http.Client the GitHub and Prow clients should use.
+func (f *httpFlags) client() (*http.Client, error) {
+	f.transport = &transport.Transport{
+		Default:        transport.HostLimit{Concurrency: *f.hostConcurrency},
+		Hosts:          f.hostLimits,
+		MaxRetries:     *f.retries,
+		Backoff:        time.Second,
+		RequestTimeout: *f.requestTimeout,
+	}
+
+	var err error
+	switch {
+	case *f.record != "" && *f.replay != "":
+		return nil, fmt.Errorf("-record and -replay cannot be used together")
+	case *f.replay != "":
+		f.cassette, err = cassette.New(*f.replay, cassette.Replay)
+		if err != nil {
+			return nil, err
+		}
+		return f.cassette.Client(), nil
+	case *f.record != "":
+		f.cassette, err = cassette.New(*f.record, cassette.Record)
+		if err != nil {
+			return nil, err
+		}
+		f.cassette.Transport = f.transport
+		return f.cassette.Client(), nil
+	case *f.noCache:
+		return f.transport.Client(), nil
+	}
+	f.cache, err = httpcache.New(*f.cacheDir, *f.cacheTTL)
+	if err != nil {
+		return nil, err
+	}
+	f.cache.Transport = f.transport
+	return f.cache.Client(), nil
+}
+
+// finish logs the per-stage request statistics and how the cache served the
+// run, and fails a replay that needed requests the cassette does not hold, so
+// its report is not mistaken for a faithful reproduction.
+func (f *httpFlags) finish() error {
+	if f.transport != nil {
+		stats := f.transport.Stats()
+		sort.SliceStable(stats, func(i, j int) bool {
+			return analysis.StageOrder(stats[i].Stage) < analysis.StageOrder(stats[j].Stage)
+		})
+		report.PrintStageStats(os.Stderr, stats)
+	}
+	if f.cache != nil {
+		stats := f.cache.Stats()
+		log.Printf("HTTP cache: %d hits, %d revalidated, %d fetched", stats.Hits, stats.Revalidated, stats.Misses)
+	}
+	if f.cassette != nil && f.cassette.Mode == cassette.Replay {
+		if missing := f.cassette.Missing(); len(missing) > 0 {
+			return fmt.Errorf("cassette %s has no recording of %d requests:\n\t%s", *f.replay, len(missing), strings.Join(missing, "\n\t"))
+		}
+	}
+	return nil
+}
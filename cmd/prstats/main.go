@@ -0,0 +1,67 @@
+// Command prstats reports on the CI cost and health of GitHub pull requests
+// tested by Prow.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+var commands = []command{
+	{"pr-costs", "pr-costs [flags] <org> <repo> <start-date> <end-date> | <org> <repo> <period>...", runPRCosts},
+	{"run", "run [flags] <config.yaml>", runConfig},
+	{"presubmits", "presubmits [flags] <project>", runPresubmits},
+	{"reprice", "reprice [flags] <pr-costs.json>", runReprice},
+	{"diff", "diff [flags] <old.json> <new.json>", runDiff},
+	{"cache", "cache [flags] inspect|purge", runCache},
+}
+
+// exitError is an error that exits prstats with a status the command chose,
+// such as 3 when diff finds the reports differ. Other errors exit with 1.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: prstats <command> [arguments]")
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %s\n", c.usage)
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	for _, c := range commands {
+		if c.name == os.Args[1] {
+			if err := c.run(os.Args[2:]); err != nil {
+				var exit *exitError
+				if errors.As(err, &exit) {
+					log.Printf("%s: %v", c.name, err)
+					os.Exit(exit.code)
+				}
+				log.Fatalf("%s: %v", c.name, err)
+			}
+			return
+		}
+	}
+	usage()
+	os.Exit(2)
+}
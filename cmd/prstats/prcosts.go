@@ -0,0 +1,430 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"cix/pkg/analysis"
+	"cix/pkg/cost"
+	"cix/pkg/github"
+	"cix/pkg/period"
+	"cix/pkg/report"
+	"cix/pkg/store"
+	"cix/pkg/transport"
+)
+
+// costFlags are the flags shared by the commands that run cost analyses.
+type costFlags struct {
+	tokenFile        *string
+	githubAPI        *string
+	githubURL        *string
+	prow             prowFlags
+	http             *httpFlags
+	rateCardFile     *string
+	unfinishedPolicy *string
+	runWorkers       *int
+	timingWorkers    *int
+	commentWorkers   *int
+	noProgress       *bool
+	maxFailed        *int
+	stepTimings      *bool
+	measureOverhead  *bool
+	storePath        *string
+	refresh          *bool
+	alpha            *float64
+	formats          *formatsFlag
+}
+
+func addCostFlags(fs *flag.FlagSet) *costFlags {
+	return &costFlags{
+		tokenFile:        fs.String("github-token-file", "", "file holding a GitHub token (defaults to $GITHUB_TOKEN or $GH_TOKEN)"),
+		githubAPI:        fs.String("github-api", "rest", "GitHub API to discover PRs and comments with: rest or graphql"),
+		githubURL:        fs.String("github-url", "", "GitHub API base URL (defaults to https://api.github.com)"),
+		prow:             addProwFlags(fs),
+		http:             addHTTPFlags(fs),
+		rateCardFile:     fs.String("rate-card", "", "YAML rate card to price jobs with (defaults to the built-in one)"),
+		unfinishedPolicy: fs.String("unfinished", cost.PolicyExclude, "how to price runs without a finish time: exclude, abort-time (still running runs are priced up to now) or median"),
+		runWorkers:       fs.Int("run-workers", analysis.DefaultWorkers.Runs, "PRs whose job runs are listed at once"),
+		timingWorkers:    fs.Int("timing-workers", analysis.DefaultWorkers.Timings, "job runs whose timings are fetched at once"),
+		commentWorkers:   fs.Int("comment-workers", analysis.DefaultWorkers.Comments, "PRs whose comments are fetched at once"),
+		noProgress:       fs.Bool("no-progress", false, "do not show the live progress line, which is only shown when stderr is a terminal"),
+		maxFailed:        fs.Int("max-failed-prs", 0, "number of PRs that may fail, with their cost incomplete, before the run exits with an error"),
+		stepTimings:      fs.Bool("step-timings", false, "read each run's step graph to price only the window its cloud resources existed and break costs down by step"),
+		measureOverhead:  fs.Bool("measure-overhead", false, "measure the time before cloud resources are provisioned from each run's step graph instead of using the rate card's overhead model"),
+		storePath:        fs.String("store", "", "file keeping processed PRs and finished job runs between runs, so only new or changed ones are fetched"),
+		refresh:          fs.Bool("refresh", false, "ignore what -store holds and fetch everything again"),
+		formats:          addFormatFlag(fs),
+		alpha:            fs.Float64("alpha", cost.DefaultAlpha, "p-value below which a change between periods is reported as significant"),
+	}
+}
+
+// costRunner runs cost analyses of one repo and period at a time, sharing
+// the GitHub source, HTTP client and store between them.
+type costRunner struct {
+	flags      *costFlags
+	httpClient *http.Client
+	source     github.Source
+	store      *store.Store
+	// where the text report is printed
+	text io.Writer
+}
+
+// newCostRunner checks the flags and sets up what every analysis shares.
+func newCostRunner(flags *costFlags) (*costRunner, error) {
+	switch *flags.unfinishedPolicy {
+	case cost.PolicyExclude, cost.PolicyAbortTime, cost.PolicyMedian:
+	default:
+		return nil, fmt.Errorf("unknown -unfinished policy %q", *flags.unfinishedPolicy)
+	}
+
+	token, err := github.LoadToken(*flags.tokenFile)
+	if err != nil {
+		return nil, err
+	}
+	if token == "" {
+		log.Printf("No GitHub token found, requests are limited to 60 per hour")
+	}
+
+	r := &costRunner{flags: flags, text: os.Stdout}
+	r.httpClient, err = flags.http.client()
+	if err != nil {
+		return nil, err
+	}
+	r.source, err = newGitHubSource(*flags.githubAPI, *flags.githubURL, token, r.httpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	if *flags.storePath != "" {
+		r.store, err = store.Open(*flags.storePath, *flags.refresh)
+		if err != nil {
+			return nil, err
+		}
+		prs, runs := r.store.Len()
+		log.Printf("Loaded %d PRs and %d job runs from %s", prs, runs, *flags.storePath)
+	}
+	return r, nil
+}
+
+// costJob is the analysis of one repo over one period.
+type costJob struct {
+	org, repo  string
+	start, end time.Time
+	// Prow profile name, empty to pick it by repo
+	prowProfile string
+	// rate card file, empty for the built-in one
+	rateCardFile string
+	filter       analysis.Filter
+	// partial report of an earlier run to complete, if any
+	resumed *cost.Report
+}
+
+// run analyses a repo. When ctx is done part way, the report is returned
+// marked partial along with the context's error.
+func (r *costRunner) run(ctx context.Context, job costJob) (*cost.Report, error) {
+	rateCard, err := loadRateCard(job.rateCardFile)
+	if err != nil {
+		return nil, err
+	}
+	prowClient, err := r.flags.prow.clientFor(job.org, job.repo, job.prowProfile, r.httpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	pullRequests, err := r.source.GetClosedPullRequests(transport.WithStage(ctx, analysis.StageDiscover), job.org, job.repo, job.start, job.end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pull requests of %s/%s: %v", job.org, job.repo, err)
+	}
+	pullRequests = job.filter.PullRequests(pullRequests)
+	if job.resumed != nil {
+		pullRequests = skipProcessed(pullRequests, job.resumed.PRKeys())
+		log.Printf("Resuming %s/%s: %d PRs done, %d left", job.org, job.repo, len(job.resumed.PRs), len(pullRequests))
+	}
+
+	fmt.Fprintf(r.text, "%s/%s: %d Pull Requests closed between %s and %s\n", job.org, job.repo, len(pullRequests), job.start, job.end)
+	prCosts := &analysis.PRCosts{
+		Source:           r.source,
+		Prow:             prowClient,
+		RateCard:         rateCard,
+		StepTimings:      *r.flags.stepTimings,
+		MeasureOverhead:  *r.flags.measureOverhead,
+		UnfinishedPolicy: *r.flags.unfinishedPolicy,
+		Store:            r.store,
+		Filter:           job.filter,
+		Workers: analysis.Workers{
+			Runs:     *r.flags.runWorkers,
+			Timings:  *r.flags.timingWorkers,
+			Comments: *r.flags.commentWorkers,
+		},
+	}
+	// the line is redrawn with escape codes, which only a terminal
+	// understands
+	if !*r.flags.noProgress && isTerminal(os.Stderr) {
+		prCosts.Progress = analysis.NewProgressLine(os.Stderr)
+		log.SetOutput(prCosts.Progress)
+		defer log.SetOutput(os.Stderr)
+	}
+	prInfos, interrupted := prCosts.ProcessPullRequests(ctx, pullRequests)
+	if r.store != nil {
+		if err := r.store.Save(); err != nil {
+			return nil, err
+		}
+	}
+
+	costReport := &cost.Report{
+		GeneratedAt: time.Now().UTC(),
+		RateCard:    rateCard,
+		PRs:         prInfos,
+	}
+	if interrupted != nil {
+		costReport.Partial = true
+		costReport.Interrupted = interrupted.Error()
+		costReport.Unprocessed = unprocessedURLs(pullRequests, costReport.PRKeys())
+	}
+	if job.resumed != nil {
+		costReport.PRs = append(job.resumed.PRs, costReport.PRs...)
+		sort.Slice(costReport.PRs, func(i, j int) bool {
+			return costReport.PRs[i].TotalCost > costReport.PRs[j].TotalCost
+		})
+	}
+	return costReport, interrupted
+}
+
+// writeReport writes a report to every output and tells how to complete it
+// when it is partial.
+func writeReport(outputs []report.Output, costReport *cost.Report, resumeHint string) error {
+	if err := report.WritePRCosts(outputs, costReport); err != nil {
+		return err
+	}
+	if costReport.Partial {
+		path := report.JSONPath(outputs)
+		log.Printf("Run interrupted (%s), wrote a PARTIAL report of %d PRs to %s; %d PRs were not processed, complete it with %s",
+			costReport.Interrupted, len(costReport.PRs), path, len(costReport.Unprocessed), resumeHint)
+	}
+	return nil
+}
+
+func runPRCosts(args []string) error {
+	fs := flag.NewFlagSet("pr-costs", flag.ExitOnError)
+	output := fs.String("o", "pr_costs.json", "file to write the PR cost JSON to; with several periods, each period's name is added to it")
+	flags := addCostFlags(fs)
+	resume := fs.String("resume", "", "partial report of an interrupted run to complete; its PRs are not processed again")
+	sprints := fs.String("sprints", "", "YAML calendar of sprints that can be given as periods")
+	fs.Parse(args)
+
+	if fs.NArg() < 3 {
+		return fmt.Errorf("usage: prstats pr-costs [flags] <org> <repo> <start-date> <end-date> | <org> <repo> <period>...")
+	}
+
+	var calendar period.Calendar
+	if *sprints != "" {
+		var err error
+		calendar, err = period.LoadCalendar(*sprints)
+		if err != nil {
+			return err
+		}
+	}
+	owner, repo, periods, err := parseArgs(fs.Args(), calendar)
+	if err != nil {
+		return fmt.Errorf("failed to parse arguments: %v", err)
+	}
+	if *resume != "" && len(periods) > 1 {
+		return fmt.Errorf("-resume completes the report of a single period")
+	}
+	if err := flags.formats.check(len(periods) == 1); err != nil {
+		return err
+	}
+
+	runner, err := newCostRunner(flags)
+	if err != nil {
+		return err
+	}
+	runner.text = flags.formats.text(*output)
+	text := runner.text
+
+	ctx, cancel := flags.http.context()
+	defer cancel()
+
+	failed := 0
+	var interrupted error
+	var periodPRs []cost.PeriodPRs
+	for _, p := range periods {
+		job := costJob{
+			org:          owner,
+			repo:         repo,
+			start:        p.Start,
+			end:          p.End,
+			prowProfile:  *flags.prow.profile,
+			rateCardFile: *flags.rateCardFile,
+		}
+		if *resume != "" {
+			job.resumed, err = cost.ReadReport(*resume)
+			if err != nil {
+				return err
+			}
+		}
+		path := *output
+		if len(periods) > 1 {
+			path = periodPath(*output, p.Name)
+		}
+		outputs, err := flags.formats.outputs(path)
+		if err != nil {
+			return err
+		}
+		path = report.JSONPath(outputs)
+
+		var costReport *cost.Report
+		costReport, interrupted = runner.run(ctx, job)
+		if costReport == nil {
+			return interrupted
+		}
+		if err := writeReport(outputs, costReport, "-resume "+path); err != nil {
+			return err
+		}
+		prInfos := costReport.PRs
+		if len(periods) > 1 {
+			fmt.Fprintf(text, "\nPeriod %s, written to %s:\n", p.Name, path)
+		}
+		report.PrintPRCosts(text, prInfos)
+		report.PrintRepoSpend(text, cost.SpendByRepo(prInfos))
+		if *flags.stepTimings {
+			report.PrintStepCosts(text, cost.StepTotals(prInfos))
+		}
+		failed += report.PrintProblems(text, prInfos)
+
+		periodPRs = append(periodPRs, cost.PeriodPRs{
+			Period:  p.Name,
+			Start:   p.Start,
+			End:     p.End,
+			Report:  path,
+			PRs:     prInfos,
+			Partial: costReport.Partial,
+		})
+		if interrupted != nil {
+			break
+		}
+	}
+
+	if len(periodPRs) > 1 {
+		comparison := cost.ComparePeriods(owner, repo, periodPRs, *flags.alpha)
+		comparisonReport := &cost.ComparisonReport{
+			GeneratedAt: time.Now().UTC(),
+			Repos:       []cost.Comparison{comparison},
+			Partial:     interrupted != nil,
+		}
+		path := periodPath(*output, "comparison")
+		if err := report.WriteJSON(path, comparisonReport); err != nil {
+			return err
+		}
+		report.PrintPeriodComparison(text, comparisonReport)
+		log.Printf("Wrote comparison of %d periods to %s", len(periodPRs), path)
+	}
+
+	if err := flags.http.finish(); err != nil {
+		return err
+	}
+	if failed > *flags.maxFailed {
+		return fmt.Errorf("%d PRs failed, more than the %d allowed by -max-failed-prs", failed, *flags.maxFailed)
+	}
+	if interrupted != nil {
+		return fmt.Errorf("partial report written: %v", interrupted)
+	}
+	return nil
+}
+
+// periodPath adds the name of a period to a report file name, turning
+// pr_costs.json into pr_costs_2023-Q3.json.
+func periodPath(path, name string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "_" + name + ext
+}
+
+// skipProcessed returns the PRs whose org/repo#number is not in done.
+func skipProcessed(pullRequests []github.PullRequest, done map[string]bool) []github.PullRequest {
+	var left []github.PullRequest
+	for _, pr := range pullRequests {
+		if !done[prKey(pr)] {
+			left = append(left, pr)
+		}
+	}
+	return left
+}
+
+// unprocessedURLs returns the URLs of the PRs whose org/repo#number is not in
+// done.
+func unprocessedURLs(pullRequests []github.PullRequest, done map[string]bool) []string {
+	var urls []string
+	for _, pr := range skipProcessed(pullRequests, done) {
+		urls = append(urls, pr.URL)
+	}
+	return urls
+}
+
+func prKey(pr github.PullRequest) string {
+	org, repo, prNum, _ := github.ExtractPRInfo(pr.URL)
+	return fmt.Sprintf("%s/%s#%d", org, repo, prNum)
+}
+
+// isTerminal reports whether f is a terminal rather than a file or pipe.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func loadRateCard(path string) (*cost.RateCard, error) {
+	if path == "" {
+		return cost.DefaultRateCard(), nil
+	}
+	return cost.LoadRateCard(path)
+}
+
+func newGitHubSource(api, baseURL, token string, httpClient *http.Client) (github.Source, error) {
+	client := github.NewClient(token)
+	if httpClient != nil {
+		client.SetHTTPClient(httpClient)
+	}
+	if baseURL != "" {
+		client.SetBaseURL(baseURL)
+	}
+	switch api {
+	case "rest":
+		return client, nil
+	case "graphql":
+		if token == "" {
+			log.Printf("The GitHub GraphQL API does not accept anonymous requests")
+		}
+		return github.NewGraphQLClient(client), nil
+	}
+	return nil, fmt.Errorf("unknown GitHub API %q, expected rest or graphql", api)
+}
+
+// parseArgs reads the org, repo and periods of the pr-costs command. The
+// periods are either a start and an end date or any number of named periods.
+func parseArgs(args []string, calendar period.Calendar) (string, string, []period.Period, error) {
+	owner := args[0]
+	repo := args[1]
+	if len(args) == 4 {
+		startDate, startErr := period.ParseDate(args[2])
+		endDate, endErr := period.ParseDate(args[3])
+		switch {
+		case startErr == nil && endErr == nil:
+			return owner, repo, []period.Period{period.Range(startDate, endDate)}, nil
+		case startErr == nil:
+			return "", "", nil, fmt.Errorf("invalid end date: %v", endErr)
+		}
+	}
+	periods, err := period.ParseAll(args[2:], time.Now(), calendar)
+	if err != nil {
+		return "", "", nil, err
+	}
+	return owner, repo, periods, nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"cix/pkg/cost"
+)
+
+// TestPRCostsReplay runs pr-costs against testdata/cassette. Its exchanges
+// are synthetic: they were recorded with -record against a fake transport
+// serving a closed PR with a complete AWS run, a unit run without a cluster
+// and a bot and a human retest.
+func TestPRCostsReplay(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "")
+	t.Setenv("GH_TOKEN", "")
+	output := filepath.Join(t.TempDir(), "pr_costs.json")
+	err := runPRCosts([]string{
+		"-replay", "testdata/cassette", "-no-progress", "-o", output,
+		"openshift", "ovn-kubernetes", "07-01-2023", "07-04-2023",
+	})
+	if err != nil {
+		t.Fatalf("pr-costs failed: %v", err)
+	}
+
+	costReport, err := cost.ReadReport(output)
+	if err != nil {
+		t.Fatalf("failed to read the report: %v", err)
+	}
+	if costReport.Partial || len(costReport.PRs) != 1 {
+		t.Fatalf("report is partial: %t, with %d PRs, want a complete report of 1 PR", costReport.Partial, len(costReport.PRs))
+	}
+	prInfo := costReport.PRs[0]
+	if prInfo.PRNum != 1700 || len(prInfo.Errors) != 0 {
+		t.Errorf("PR %d has errors %v, want PR 1700 without errors", prInfo.PRNum, prInfo.Errors)
+	}
+	if prInfo.URL != "https://github.com/openshift/ovn-kubernetes/pull/1700" {
+		t.Errorf("PR page is %q, want the html_url of the search result", prInfo.URL)
+	}
+	// two hours less the default overhead, at the built-in aws rate
+	if len(prInfo.Jobs) != 2 || prInfo.PlatformHours[cost.AWS] != 1.5 {
+		t.Errorf("PR has %d runs and %v aws hours, want 2 runs and 1.5 hours", len(prInfo.Jobs), prInfo.PlatformHours[cost.AWS])
+	}
+	wantCoverage := cost.Coverage{Runs: 2, Complete: 1, NoCluster: 1}
+	if prInfo.Coverage != wantCoverage {
+		t.Errorf("Coverage = %+v, want %+v", prInfo.Coverage, wantCoverage)
+	}
+	if prInfo.BotRetests != 1 || prInfo.HumanRetests != 1 {
+		t.Errorf("PR has %d bot and %d human retests, want 1 and 1", prInfo.BotRetests, prInfo.HumanRetests)
+	}
+}
+
+func TestPRCostsReplayMissing(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "")
+	t.Setenv("GH_TOKEN", "")
+	// the cassette holds no search of this period
+	err := runPRCosts([]string{
+		"-replay", "testdata/cassette", "-no-progress", "-o", filepath.Join(t.TempDir(), "pr_costs.json"),
+		"openshift", "ovn-kubernetes", "08-01-2023", "08-04-2023",
+	})
+	if err == nil {
+		t.Errorf("pr-costs replayed requests the cassette does not hold without failing")
+	}
+}
+
+func TestPRCostsReplayFormatToStdout(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "")
+	t.Setenv("GH_TOKEN", "")
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+	read := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		read <- data
+	}()
+
+	err = runPRCosts([]string{
+		"-replay", "testdata/cassette", "-no-progress", "-o", filepath.Join(t.TempDir(), "pr_costs.json"), "-format", "csv=-",
+		"openshift", "ovn-kubernetes", "07-01-2023", "07-04-2023",
+	})
+	w.Close()
+	os.Stdout = stdout
+	data := <-read
+	if err != nil {
+		t.Fatalf("pr-costs failed: %v", err)
+	}
+
+	// only the CSV is written to stdout, the text report goes to stderr
+	rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatalf("stdout is not CSV: %v\n%s", err, data)
+	}
+	if len(rows) != 4 || rows[0][0] != "kind" || rows[1][0] != "pr" {
+		t.Errorf("stdout holds %d rows starting with %v, want a header, a PR and 2 runs", len(rows), rows[0])
+	}
+}
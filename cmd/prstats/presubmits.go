@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"cix/pkg/analysis"
+	"cix/pkg/report"
+)
+
+func runPresubmits(args []string) error {
+	fs := flag.NewFlagSet("presubmits", flag.ExitOnError)
+	output := fs.String("o", "presubmit_jobs.json", "file to write the presubmit JSON to")
+	org := fs.String("org", "openshift", "GitHub org of the project")
+	prowFlags := addProwFlags(fs)
+	httpFlags := addHTTPFlags(fs)
+	depth := fs.Int("depth", analysis.ResultsDepth, "number of older job-history pages to look at (20 runs per page)")
+	formats := addFormatFlag(fs)
+	fs.Parse(args)
+
+	if fs.NArg() < 1 {
+		return fmt.Errorf("please provide the project name for presubmit analysis")
+	}
+
+	outputs, err := formats.outputs(*output)
+	if err != nil {
+		return err
+	}
+
+	httpClient, err := httpFlags.client()
+	if err != nil {
+		return err
+	}
+
+	prowClient, err := prowFlags.client(*org, fs.Arg(0), httpClient)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := httpFlags.context()
+	defer cancel()
+
+	jobs, err := analysis.AnalyzePresubmits(ctx, prowClient, *org, fs.Arg(0), *depth)
+	if err != nil {
+		return err
+	}
+	report.PrintPresubmits(formats.text(*output), jobs)
+
+	if err := report.WritePresubmits(outputs, jobs); err != nil {
+		return err
+	}
+	return httpFlags.finish()
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+
+	"cix/pkg/prow"
+)
+
+type prowFlags struct {
+	profile      *string
+	profilesFile *string
+}
+
+func addProwFlags(fs *flag.FlagSet) prowFlags {
+	return prowFlags{
+		profile:      fs.String("prow-profile", "", "Prow deployment profile to use (defaults to the one listing the repo, else openshift)"),
+		profilesFile: fs.String("prow-profiles", "", "YAML file with additional Prow deployment profiles"),
+	}
+}
+
+// client returns a Prow client for the profile named on the command line or,
+// without one, the profile that lists org/repo. Its requests are sent with
+// httpClient when it is set.
+func (f prowFlags) client(org, repo string, httpClient *http.Client) (*prow.Client, error) {
+	return f.clientFor(org, repo, *f.profile, httpClient)
+}
+
+// clientFor is client with the profile named by profileName rather than on
+// the command line.
+func (f prowFlags) clientFor(org, repo, profileName string, httpClient *http.Client) (*prow.Client, error) {
+	profile, err := f.profileFor(org, repo, profileName)
+	if err != nil {
+		return nil, err
+	}
+	client := prow.NewClient(profile)
+	if httpClient != nil {
+		client.HTTPClient = httpClient
+	}
+	return client, nil
+}
+
+func (f prowFlags) profileFor(org, repo, profileName string) (prow.Profile, error) {
+	profiles := prow.DefaultProfiles
+	if *f.profilesFile != "" {
+		var err error
+		profiles, err = prow.LoadProfiles(*f.profilesFile)
+		if err != nil {
+			return prow.Profile{}, err
+		}
+	}
+	if profileName == "" {
+		return profiles.ForRepo(org, repo), nil
+	}
+	return profiles.Get(profileName)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"cix/pkg/analysis"
+	"cix/pkg/cost"
+	"cix/pkg/report"
+)
+
+func runReprice(args []string) error {
+	fs := flag.NewFlagSet("reprice", flag.ExitOnError)
+	output := fs.String("o", "pr_costs_repriced.json", "file to write the repriced PR cost JSON to")
+	rateCardFile := fs.String("rate-card", "", "YAML rate card to price jobs with (defaults to the built-in one)")
+	formats := addFormatFlag(fs)
+	fs.Parse(args)
+
+	if fs.NArg() < 1 {
+		return fmt.Errorf("usage: prstats reprice [flags] <pr-costs.json>")
+	}
+
+	outputs, err := formats.outputs(*output)
+	if err != nil {
+		return err
+	}
+
+	rateCard, err := loadRateCard(*rateCardFile)
+	if err != nil {
+		return err
+	}
+
+	costReport, err := cost.ReadReport(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	for _, warning := range analysis.Reprice(costReport, rateCard) {
+		log.Print(warning)
+	}
+
+	if err := report.WritePRCosts(outputs, costReport); err != nil {
+		return err
+	}
+	text := formats.text(*output)
+	report.PrintPRCosts(text, costReport.PRs)
+	report.PrintRepoSpend(text, cost.SpendByRepo(costReport.PRs))
+	return nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"cix/pkg/config"
+	"cix/pkg/cost"
+	"cix/pkg/report"
+)
+
+func runConfig(args []string) error {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	flags := addCostFlags(fs)
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: prstats run [flags] <config.yaml>")
+	}
+	cfg, err := config.Load(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	// -prow-profile replaces the config's default profile; repos naming
+	// their own profile keep it
+	if *flags.prow.profile != "" {
+		cfg.ProwProfile = *flags.prow.profile
+	}
+	periods, err := cfg.GetPeriods(time.Now())
+	if err != nil {
+		return err
+	}
+	if err := flags.formats.check(false); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(cfg.Path(cfg.OutputDir), 0o755); err != nil {
+		return fmt.Errorf("failed to create output directory: %v", err)
+	}
+
+	runner, err := newCostRunner(flags)
+	if err != nil {
+		return err
+	}
+	// the JSON reports always go to files in the output directory
+	runner.text = flags.formats.text("")
+	text := runner.text
+	ctx, cancel := flags.http.context()
+	defer cancel()
+
+	failed := 0
+	var interrupted error
+	// the PRs of every repo, period after period
+	periodPRs := make([][]cost.PeriodPRs, len(cfg.Repos))
+	for _, p := range periods {
+		combined := &cost.CombinedReport{
+			GeneratedAt: time.Now().UTC(),
+			Period:      p.Name,
+			Start:       p.Start,
+			End:         p.End,
+		}
+		for i, repo := range cfg.Repos {
+			path := cfg.ReportPath(p.Name, repo)
+			job := costJob{
+				org:          repo.Org,
+				repo:         repo.Repo,
+				start:        p.Start,
+				end:          p.End,
+				prowProfile:  cfg.ProwProfileFor(repo),
+				rateCardFile: cfg.RateCardFor(repo),
+				filter:       cfg.FilterFor(repo),
+			}
+			// a partial report left by an interrupted run is completed
+			// rather than started over
+			if existing, err := cost.ReadReport(path); err == nil && existing.Partial {
+				job.resumed = existing
+			}
+
+			var costReport *cost.Report
+			costReport, interrupted = runner.run(ctx, job)
+			if costReport == nil {
+				return interrupted
+			}
+			outputs, err := flags.formats.outputs(path)
+			if err != nil {
+				return err
+			}
+			path = report.JSONPath(outputs)
+			if err := writeReport(outputs, costReport, "the same command"); err != nil {
+				return err
+			}
+			log.Printf("Wrote %s/%s report of %d PRs for %s to %s", repo.Org, repo.Repo, len(costReport.PRs), p.Name, path)
+			failed += report.PrintProblems(text, costReport.PRs)
+
+			summary := cost.Summarize(repo.Org, repo.Repo, costReport.PRs)
+			summary.Report = path
+			summary.Partial = costReport.Partial
+			combined.Repos = append(combined.Repos, summary)
+			combined.Partial = combined.Partial || costReport.Partial
+			periodPRs[i] = append(periodPRs[i], cost.PeriodPRs{
+				Period:  p.Name,
+				Start:   p.Start,
+				End:     p.End,
+				Report:  path,
+				PRs:     costReport.PRs,
+				Partial: costReport.Partial,
+			})
+			if interrupted != nil {
+				break
+			}
+		}
+
+		combinedPath := cfg.CombinedPath(p.Name)
+		if err := report.WriteJSON(combinedPath, combined); err != nil {
+			return err
+		}
+		report.PrintRepoComparison(text, combined)
+		log.Printf("Wrote combined report for %s to %s", p.Name, combinedPath)
+		if interrupted != nil {
+			break
+		}
+	}
+
+	if len(periods) > 1 {
+		comparisonReport := &cost.ComparisonReport{
+			GeneratedAt: time.Now().UTC(),
+			Partial:     interrupted != nil,
+		}
+		for i, repo := range cfg.Repos {
+			if len(periodPRs[i]) > 1 {
+				comparisonReport.Repos = append(comparisonReport.Repos,
+					cost.ComparePeriods(repo.Org, repo.Repo, periodPRs[i], *flags.alpha))
+			}
+		}
+		path := cfg.ComparisonPath(periods)
+		if err := report.WriteJSON(path, comparisonReport); err != nil {
+			return err
+		}
+		report.PrintPeriodComparison(text, comparisonReport)
+		log.Printf("Wrote comparison of %d periods to %s", len(periods), path)
+	}
+
+	if err := flags.http.finish(); err != nil {
+		return err
+	}
+	if failed > *flags.maxFailed {
+		return fmt.Errorf("%d PRs failed, more than the %d allowed by -max-failed-prs", failed, *flags.maxFailed)
+	}
+	if interrupted != nil {
+		return fmt.Errorf("partial reports written, run again to complete them: %v", interrupted)
+	}
+	return nil
+}
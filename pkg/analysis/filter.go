@@ -0,0 +1,76 @@
+package analysis
+
+import (
+	"strings"
+
+	"cix/pkg/github"
+)
+
+// Filter narrows down the PRs and job runs a cost run looks at. The zero
+// Filter keeps everything.
+type Filter struct {
+	// skip PRs closed without being merged
+	MergedOnly bool `yaml:"merged_only"`
+	// when set, keep only PRs with at least one of these labels
+	IncludeLabels []string `yaml:"include_labels"`
+	// skip PRs with any of these labels
+	ExcludeLabels []string `yaml:"exclude_labels"`
+	// skip job runs whose job name contains any of these
+	ExcludeJobs []string `yaml:"exclude_jobs"`
+}
+
+// PullRequests returns the PRs the filter keeps.
+func (f Filter) PullRequests(pullRequests []github.PullRequest) []github.PullRequest {
+	var kept []github.PullRequest
+	for _, pr := range pullRequests {
+		if f.keepPR(pr) {
+			kept = append(kept, pr)
+		}
+	}
+	return kept
+}
+
+func (f Filter) keepPR(pr github.PullRequest) bool {
+	if f.MergedOnly && !pr.Merged() {
+		return false
+	}
+	labels := make(map[string]bool, len(pr.Labels))
+	for _, label := range pr.Labels {
+		labels[label.Name] = true
+	}
+	for _, label := range f.ExcludeLabels {
+		if labels[label] {
+			return false
+		}
+	}
+	if len(f.IncludeLabels) == 0 {
+		return true
+	}
+	for _, label := range f.IncludeLabels {
+		if labels[label] {
+			return true
+		}
+	}
+	return false
+}
+
+// KeepJob reports whether the runs of a job are looked at.
+func (f Filter) KeepJob(jobName string) bool {
+	for _, exclude := range f.ExcludeJobs {
+		if strings.Contains(jobName, exclude) {
+			return false
+		}
+	}
+	return true
+}
+
+// excludesAll reports whether f leaves out every job the excludeJobs patterns
+// leave out, so runs listed with those patterns include every run f keeps.
+func (f Filter) excludesAll(excludeJobs []string) bool {
+	for _, exclude := range excludeJobs {
+		if f.KeepJob(exclude) {
+			return false
+		}
+	}
+	return true
+}
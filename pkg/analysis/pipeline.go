@@ -0,0 +1,316 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"cix/pkg/cost"
+	"cix/pkg/github"
+	"cix/pkg/transport"
+)
+
+// Pipeline stages after the PRs are discovered. Requests are tagged with
+// their stage so transport statistics can be reported per stage.
+const (
+	StageDiscover = "discover"
+	StageRuns     = "runs"
+	StageTimings  = "timings"
+	StageComments = "comments"
+)
+
+// Workers is the number of goroutines working on each stage. The runs stage
+// reads deck, the timings stage gcsweb and the comments stage GitHub, so each
+// can be sized for its host.
+type Workers struct {
+	Runs     int
+	Timings  int
+	Comments int
+}
+
+// DefaultWorkers is used for every stage left at zero.
+var DefaultWorkers = Workers{Runs: 5, Timings: 20, Comments: 5}
+
+func (w Workers) withDefaults() Workers {
+	if w.Runs <= 0 {
+		w.Runs = DefaultWorkers.Runs
+	}
+	if w.Timings <= 0 {
+		w.Timings = DefaultWorkers.Timings
+	}
+	if w.Comments <= 0 {
+		w.Comments = DefaultWorkers.Comments
+	}
+	return w
+}
+
+type runTask struct {
+	work  *prWork
+	index int
+}
+
+// ProcessPullRequests builds the cost information of every pull request and
+// returns it sorted from most to least expensive. PRs flow through three
+// stages, each with its own workers: listing job runs, fetching the timings
+// of every run, and fetching comments. When ctx is done before every PR is
+// processed, the PRs completed so far are returned along with the context's
+// error.
+func (a *PRCosts) ProcessPullRequests(ctx context.Context, pullRequests []github.PullRequest) ([]cost.PRInfo, error) {
+	workers := a.Workers.withDefaults()
+	progress := newProgress(a.Progress, len(pullRequests))
+	defer progress.stop()
+
+	prs := make(chan github.PullRequest)
+	listed := make(chan *prWork)
+	tasks := make(chan runTask)
+	measured := make(chan *prWork)
+	results := make(chan *cost.PRInfo)
+
+	go func() {
+		defer close(prs)
+		for _, pr := range pullRequests {
+			select {
+			case prs <- pr:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// PRs without runs skip the timings stage straight to measured
+	var listing, toResults sync.WaitGroup
+	runsCtx := transport.WithStage(ctx, StageRuns)
+	listing.Add(workers.Runs)
+	toResults.Add(workers.Runs)
+	for i := 0; i < workers.Runs; i++ {
+		go func() {
+			defer listing.Done()
+			defer toResults.Done()
+			for pr := range prs {
+				work, prInfo := a.listRuns(runsCtx, pr)
+				progress.listed(work)
+				switch {
+				case prInfo != nil:
+					results <- prInfo
+				case len(work.runs) == 0:
+					measured <- work
+				default:
+					listed <- work
+				}
+			}
+		}()
+	}
+	go func() {
+		listing.Wait()
+		close(listed)
+	}()
+
+	go func() {
+		defer close(tasks)
+		for work := range listed {
+			for i := range work.runs {
+				tasks <- runTask{work: work, index: i}
+			}
+		}
+	}()
+
+	timingsCtx := transport.WithStage(ctx, StageTimings)
+	var timingsDone sync.WaitGroup
+	timingsDone.Add(workers.Timings)
+	for i := 0; i < workers.Timings; i++ {
+		go func() {
+			defer timingsDone.Done()
+			for task := range tasks {
+				work := task.work
+				work.jobs[task.index] = a.measureRun(timingsCtx, work, work.runs[task.index])
+				progress.measured()
+				if atomic.AddInt32(&work.pending, -1) == 0 {
+					measured <- work
+				}
+			}
+		}()
+	}
+	go func() {
+		// listed closes once the runs workers are done, so once tasks is
+		// drained nothing else sends to measured
+		timingsDone.Wait()
+		close(measured)
+	}()
+
+	commentsCtx := transport.WithStage(ctx, StageComments)
+	toResults.Add(workers.Comments)
+	for i := 0; i < workers.Comments; i++ {
+		go func() {
+			defer toResults.Done()
+			for work := range measured {
+				prInfo := a.finishPR(commentsCtx, work)
+				// a PR cut short by cancellation is missing data, drop it
+				if ctx.Err() != nil {
+					results <- nil
+					continue
+				}
+				results <- &prInfo
+			}
+		}()
+	}
+	go func() {
+		toResults.Wait()
+		close(results)
+	}()
+
+	prInfoSlice := make([]cost.PRInfo, 0, len(pullRequests))
+	for prInfo := range results {
+		progress.done()
+		if prInfo == nil {
+			continue
+		}
+		// Append the PRInfo to the slice
+		prInfoSlice = append(prInfoSlice, *prInfo)
+	}
+	if a.UnfinishedPolicy == cost.PolicyMedian {
+		applyMedianEstimates(prInfoSlice, a.RateCard)
+	}
+	sort.Slice(prInfoSlice, func(i, j int) bool {
+		return prInfoSlice[i].TotalCost > prInfoSlice[j].TotalCost
+	})
+	return prInfoSlice, ctx.Err()
+}
+
+// ProgressLine is a status line kept at the bottom of a terminal. Output
+// written through it, such as the log, clears the line first and redraws it
+// below, so the two never share a row.
+type ProgressLine struct {
+	w io.Writer
+
+	mu   sync.Mutex
+	line string
+}
+
+// NewProgressLine returns a ProgressLine drawn on w, which should be a
+// terminal.
+func NewProgressLine(w io.Writer) *ProgressLine {
+	return &ProgressLine{w: w}
+}
+
+func (l *ProgressLine) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.line == "" {
+		return l.w.Write(p)
+	}
+	io.WriteString(l.w, "\r\033[K")
+	n, err := l.w.Write(p)
+	io.WriteString(l.w, l.line)
+	return n, err
+}
+
+func (l *ProgressLine) show(line string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.line = line
+	fmt.Fprintf(l.w, "\r\033[K%s", line)
+}
+
+// finish leaves the last line shown on a row of its own.
+func (l *ProgressLine) finish() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.line != "" {
+		fmt.Fprintln(l.w)
+	}
+	l.line = ""
+}
+
+// progress keeps a single status line up to date with how far each stage
+// got and when the run is expected to finish.
+type progress struct {
+	line    *ProgressLine
+	total   int
+	started time.Time
+
+	listedPRs, runs, measuredRuns, donePRs int64
+
+	ticker *time.Ticker
+	quit   chan struct{}
+	wg     sync.WaitGroup
+}
+
+func newProgress(line *ProgressLine, total int) *progress {
+	p := &progress{line: line, total: total, started: time.Now()}
+	if line == nil {
+		return p
+	}
+	p.ticker = time.NewTicker(time.Second)
+	p.quit = make(chan struct{})
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		for {
+			select {
+			case <-p.ticker.C:
+				p.print()
+			case <-p.quit:
+				return
+			}
+		}
+	}()
+	return p
+}
+
+func (p *progress) listed(work *prWork) {
+	atomic.AddInt64(&p.listedPRs, 1)
+	if work != nil {
+		atomic.AddInt64(&p.runs, int64(len(work.runs)))
+	}
+}
+
+func (p *progress) measured() {
+	atomic.AddInt64(&p.measuredRuns, 1)
+}
+
+func (p *progress) done() {
+	atomic.AddInt64(&p.donePRs, 1)
+}
+
+func (p *progress) print() {
+	done := atomic.LoadInt64(&p.donePRs)
+	eta := "unknown"
+	if done > 0 {
+		elapsed := time.Since(p.started)
+		remaining := time.Duration(float64(elapsed) / float64(done) * float64(int64(p.total)-done))
+		eta = remaining.Round(time.Second).String()
+	}
+	p.line.show(fmt.Sprintf("PRs %d/%d done, %d listed | runs %d/%d measured | ETA %s",
+		done, p.total, atomic.LoadInt64(&p.listedPRs),
+		atomic.LoadInt64(&p.measuredRuns), atomic.LoadInt64(&p.runs), eta))
+}
+
+func (p *progress) stop() {
+	if p.line == nil {
+		return
+	}
+	p.ticker.Stop()
+	close(p.quit)
+	p.wg.Wait()
+	p.print()
+	p.line.finish()
+}
+
+// stageNames lists the stages in pipeline order, for reports of per-stage
+// statistics.
+var stageNames = []string{StageDiscover, StageRuns, StageTimings, StageComments}
+
+// StageOrder returns the position of a stage in the pipeline, or the number
+// of stages for anything else so it sorts last.
+func StageOrder(stage string) int {
+	for i, name := range stageNames {
+		if name == stage {
+			return i
+		}
+	}
+	return len(stageNames)
+}
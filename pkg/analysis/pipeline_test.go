@@ -0,0 +1,191 @@
+package analysis
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cix/pkg/cost"
+	"cix/pkg/github"
+	"cix/pkg/prow"
+)
+
+// fakeSource serves PR comments, calling onComments first when set.
+type fakeSource struct {
+	mu         sync.Mutex
+	calls      int
+	onComments func(call int)
+}
+
+func (s *fakeSource) GetClosedPullRequests(ctx context.Context, owner, repo string, startTime, endTime time.Time) ([]github.PullRequest, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (s *fakeSource) GetPRComments(ctx context.Context, owner, repo string, prNumber int) ([]github.Comment, error) {
+	s.mu.Lock()
+	s.calls++
+	call := s.calls
+	s.mu.Unlock()
+	if s.onComments != nil {
+		s.onComments(call)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return []github.Comment{{Body: "/retest", User: github.User{Login: "someone"}}}, nil
+}
+
+// newFakeProw serves deck and gcsweb for PRs that each have one e2e-aws run
+// lasting as many hours as the PR's number, plus the default overhead.
+func newFakeProw(t *testing.T) *prow.Client {
+	started := time.Date(2023, 7, 3, 10, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/pr-history/" {
+			pr := r.URL.Query().Get("pr")
+			fmt.Fprintf(w, `<html><head><script>var allBuilds = {"Jobs":[{"Name":"e2e-aws","Builds":[{"SpyglassLink":"/view/gs/bucket/pr-logs/%[1]s/e2e-aws/%[1]s","ID":"%[1]s","Started":"2023-07-03T10:00:00Z","Result":"SUCCESS"}]}]};</script></head></html>`, pr)
+			return
+		}
+		parts := strings.Split(r.URL.Path, "/")
+		pr, _ := strconv.Atoi(parts[len(parts)-2])
+		switch parts[len(parts)-1] {
+		case "prowjob.json":
+			fmt.Fprint(w, `{"metadata":{"labels":{"ci-operator.openshift.io/cloud":"aws"}}}`)
+		case "started.json":
+			fmt.Fprintf(w, `{"timestamp":%d}`, started.Unix())
+		case "finished.json":
+			finished := started.Add(time.Duration(pr)*time.Hour + 30*time.Minute)
+			fmt.Fprintf(w, `{"timestamp":%d,"result":"SUCCESS"}`, finished.Unix())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return prow.NewClient(prow.Profile{Name: "fake", DeckURL: server.URL, ArtifactsURL: server.URL + "/gcs", Bucket: "bucket"})
+}
+
+func testPullRequests(n int) []github.PullRequest {
+	var prs []github.PullRequest
+	for i := 1; i <= n; i++ {
+		prs = append(prs, github.PullRequest{
+			Number:    i,
+			URL:       fmt.Sprintf("https://github.com/openshift/ovn-kubernetes/pull/%d", i),
+			CreatedAt: time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC),
+			ClosedAt:  time.Date(2023, 7, 4, 0, 0, 0, 0, time.UTC),
+		})
+	}
+	return prs
+}
+
+// process runs ProcessPullRequests, failing the test if it does not return.
+func process(t *testing.T, ctx context.Context, a *PRCosts, prs []github.PullRequest) ([]cost.PRInfo, error) {
+	t.Helper()
+	type result struct {
+		prInfos []cost.PRInfo
+		err     error
+	}
+	done := make(chan result, 1)
+	go func() {
+		prInfos, err := a.ProcessPullRequests(ctx, prs)
+		done <- result{prInfos, err}
+	}()
+	select {
+	case r := <-done:
+		return r.prInfos, r.err
+	case <-time.After(10 * time.Second):
+		t.Fatalf("ProcessPullRequests() did not return")
+		return nil, nil
+	}
+}
+
+func TestProcessPullRequests(t *testing.T) {
+	a := &PRCosts{
+		Source:   &fakeSource{},
+		Prow:     newFakeProw(t),
+		RateCard: cost.DefaultRateCard(),
+		Workers:  Workers{Runs: 2, Timings: 3, Comments: 2},
+	}
+	prInfos, err := process(t, context.Background(), a, testPullRequests(8))
+	if err != nil {
+		t.Fatalf("ProcessPullRequests() failed: %v", err)
+	}
+	if len(prInfos) != 8 {
+		t.Fatalf("ProcessPullRequests() returned %d PRs, want 8", len(prInfos))
+	}
+	// from most to least expensive, which is from the longest run down
+	for i, prInfo := range prInfos {
+		if want := 8 - i; prInfo.PRNum != want || prInfo.PlatformHours[cost.AWS] != float64(want) {
+			t.Errorf("PR %d is %d with %v aws hours, want %d with %d", i, prInfo.PRNum, prInfo.PlatformHours[cost.AWS], want, want)
+		}
+		if len(prInfo.Errors) != 0 || prInfo.PRRetestCount != 1 {
+			t.Errorf("PR %d has errors %v and %d retests, want none and 1", prInfo.PRNum, prInfo.Errors, prInfo.PRRetestCount)
+		}
+	}
+}
+
+func TestProcessPullRequestsCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a := &PRCosts{
+		// the third PR to be finished is cut short
+		Source: &fakeSource{onComments: func(call int) {
+			if call == 3 {
+				cancel()
+			}
+		}},
+		Prow:     newFakeProw(t),
+		RateCard: cost.DefaultRateCard(),
+		Workers:  Workers{Runs: 2, Timings: 2, Comments: 1},
+	}
+	prInfos, err := process(t, ctx, a, testPullRequests(20))
+	if err != context.Canceled {
+		t.Errorf("ProcessPullRequests() returned %v, want %v", err, context.Canceled)
+	}
+	if len(prInfos) != 2 {
+		t.Errorf("ProcessPullRequests() returned %d PRs, want the 2 finished before cancelling", len(prInfos))
+	}
+	for _, prInfo := range prInfos {
+		if len(prInfo.Errors) != 0 {
+			t.Errorf("PR %d was returned with errors %v", prInfo.PRNum, prInfo.Errors)
+		}
+	}
+}
+
+func TestProgressLine(t *testing.T) {
+	var out bytes.Buffer
+	line := NewProgressLine(&out)
+	logger := log.New(line, "", 0)
+
+	logger.Print("before")
+	line.show("PRs 1/2 done")
+	logger.Print("warning")
+	line.finish()
+	logger.Print("after")
+
+	want := "before\n" +
+		"\r\033[KPRs 1/2 done" +
+		// the log line clears the progress line and redraws it below
+		"\r\033[Kwarning\nPRs 1/2 done" +
+		"\n" +
+		"after\n"
+	if out.String() != want {
+		t.Errorf("wrote %q, want %q", out.String(), want)
+	}
+}
+
+func TestProcessPullRequestsCancelledBeforeStart(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	a := &PRCosts{Source: &fakeSource{}, Prow: newFakeProw(t), RateCard: cost.DefaultRateCard()}
+	prInfos, err := process(t, ctx, a, testPullRequests(50))
+	if err != context.Canceled || len(prInfos) != 0 {
+		t.Errorf("ProcessPullRequests() = %d PRs, %v, want none and %v", len(prInfos), err, context.Canceled)
+	}
+}
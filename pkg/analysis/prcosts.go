@@ -0,0 +1,472 @@
+// Package analysis combines GitHub and Prow data into PR cost and presubmit
+// reports.
+package analysis
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"cix/pkg/cost"
+	"cix/pkg/github"
+	"cix/pkg/prow"
+	"cix/pkg/store"
+)
+
+// PRCosts builds the cost information of pull requests.
+type PRCosts struct {
+	Source   github.Source
+	Prow     *prow.Client
+	RateCard *cost.RateCard
+	// read each run's step graph to price only the window its cloud
+	// resources existed and break its cost down by step, instead of using
+	// the rate card's overhead model
+	StepTimings bool
+	// read the time before cloud resources are provisioned from each run's
+	// step graph instead of using the rate card's overhead model; with
+	// StepTimings this only applies to runs without a cluster window
+	MeasureOverhead bool
+	// how runs without a finish time are priced, one of the cost.Policy
+	// constants; cost.PolicyExclude when empty
+	UnfinishedPolicy string
+	// the current time, which cost.PolicyAbortTime prices still running
+	// runs up to; time.Now when nil
+	Now func() time.Time
+	// when set, PRs unchanged since they were stored and finished job runs
+	// are taken from the store instead of being fetched again
+	Store *store.Store
+	// the job runs to look at; PRs are filtered before they are passed in
+	Filter Filter
+	// workers of each pipeline stage, DefaultWorkers when zero
+	Workers Workers
+	// the live progress line, none when nil
+	Progress *ProgressLine
+}
+
+// prWork is a PR on its way through the pipeline.
+type prWork struct {
+	pr        github.PullRequest
+	org, repo string
+	prNum     int
+	runs      []prow.JobRun
+	jobs      []cost.JobInfo
+	problems  *prProblems
+	// runs still waiting for their timings
+	pending int32
+}
+
+// listRuns starts the work on a PR by listing its job runs. It returns the
+// finished PRInfo instead when the PR is taken from the store or cannot be
+// processed at all.
+func (a *PRCosts) listRuns(ctx context.Context, pr github.PullRequest) (*prWork, *cost.PRInfo) {
+	org, repo, prNum, err := github.ExtractPRInfo(pr.URL)
+	if err != nil {
+		return nil, &cost.PRInfo{Errors: []string{err.Error()}}
+	}
+	if prInfo, ok := a.storedPR(pr, org, repo, prNum); ok {
+		return nil, &prInfo
+	}
+	work := &prWork{pr: pr, org: org, repo: repo, prNum: prNum, problems: &prProblems{}}
+	runs, err := a.Prow.GetPRJobRuns(ctx, org, repo, prNum)
+	if err != nil {
+		work.problems.errorf("failed to list job runs: %v", err)
+	}
+	for _, run := range runs {
+		if a.Filter.KeepJob(run.JobName) {
+			work.runs = append(work.runs, run)
+		}
+	}
+	if len(work.runs) > 0 {
+		work.jobs = make([]cost.JobInfo, len(work.runs))
+	}
+	work.pending = int32(len(work.runs))
+	return work, nil
+}
+
+// measureRun finds the platform, timings and cost of a job run.
+func (a *PRCosts) measureRun(ctx context.Context, work *prWork, run prow.JobRun) cost.JobInfo {
+	problems := work.problems
+	if a.Store != nil {
+		if jobInfo, ok := a.Store.Run(run.URL, a.measurement()); ok {
+			if jobInfo.Platform != "" {
+				a.priceJob(&jobInfo, problems)
+			}
+			return jobInfo
+		}
+	}
+	org, repo, prNum := work.org, work.repo, work.prNum
+	platform, prowJob := a.jobPlatform(ctx, org, repo, prNum, run, problems)
+	jobInfo := cost.JobInfo{
+		JobURL:    run.URL,
+		JobName:   run.JobName,
+		Platform:  platform,
+		Status:    cost.StatusNoCluster,
+		StartTime: run.StartTime,
+	}
+	if prowJob != nil {
+		if prowJob.Status.CompletionTime != nil && run.FinishTime.IsZero() {
+			run.FinishTime = *prowJob.Status.CompletionTime
+		}
+		if run.Refs == nil {
+			run.Refs = prowJob.Spec.Refs
+		}
+	}
+	jobInfo.SHA = run.PullSHA()
+	if run.State == prow.StateAborted {
+		jobInfo.Result = "ABORTED"
+	}
+	if jobInfo.Platform != "" {
+		a.measureJob(ctx, org, repo, prNum, run, &jobInfo, problems)
+		a.priceJob(&jobInfo, problems)
+	}
+	if a.Store != nil && ctx.Err() == nil && settledRun(run, jobInfo) {
+		a.Store.PutRun(a.measurement(), jobInfo)
+	}
+	return jobInfo
+}
+
+// finishPR reads the comments of a PR whose runs are measured and builds its
+// PRInfo.
+func (a *PRCosts) finishPR(ctx context.Context, work *prWork) cost.PRInfo {
+	pr, org, repo, prNum := work.pr, work.org, work.repo, work.prNum
+	problems := work.problems
+
+	prLifespan := pr.ClosedAt.Sub(pr.CreatedAt).Hours() / 24
+	prComments, err := a.Source.GetPRComments(ctx, org, repo, prNum)
+	if err != nil {
+		problems.errorf("failed to get comments: %v", err)
+	}
+	var commands []prow.Command
+	for _, comment := range prComments {
+		commands = append(commands, prow.ParseCommands(comment.Body, comment.User.Login, comment.CreatedAt)...)
+	}
+	prRetestCount, botRetests := 0, 0
+	commandCounts := make(map[string]int)
+	retestsByAuthor := make(map[string]int)
+	retestsByJob := make(map[string]int)
+	for _, command := range commands {
+		commandCounts[command.Name]++
+		if !command.IsRetest() {
+			continue
+		}
+		prRetestCount++
+		if prow.IsBot(command.Author) {
+			botRetests++
+		}
+		retestsByAuthor[command.Author]++
+		for _, jobName := range command.MatchJobs(work.runs) {
+			retestsByJob[jobName]++
+		}
+	}
+	commentCounts := make(map[string]int)
+	for source, count := range github.CountBySource(prComments) {
+		commentCounts[string(source)] = count
+	}
+	prInfo := cost.PRInfo{
+		Org:             org,
+		Repo:            repo,
+		PRNum:           prNum,
+		URL:             pr.URL,
+		PRLifeSpan:      prLifespan,
+		PRRetestCount:   prRetestCount,
+		BotRetests:      botRetests,
+		HumanRetests:    prRetestCount - botRetests,
+		CommentCounts:   commentCounts,
+		CommandCounts:   commandCounts,
+		RetestsByAuthor: retestsByAuthor,
+		RetestsByJob:    retestsByJob,
+		Jobs:            work.jobs,
+		Errors:          problems.errors,
+		Warnings:        problems.warnings,
+	}
+	prInfo.ComputeTotals()
+	if a.Store != nil && ctx.Err() == nil {
+		a.Store.PutPR(store.PR{
+			UpdatedAt:   pr.UpdatedAt,
+			Measurement: a.measurement(),
+			ExcludeJobs: a.Filter.ExcludeJobs,
+			Info:        prInfo,
+		})
+	}
+	return prInfo
+}
+
+// storedPR returns the stored PRInfo of a PR, priced with the current rate
+// card, when the PR has not been updated since it was stored, its runs were
+// measured with the current settings, none of them could change any more and
+// the stored runs include every job the current filter keeps.
+func (a *PRCosts) storedPR(pr github.PullRequest, org, repo string, prNum int) (cost.PRInfo, bool) {
+	if a.Store == nil || pr.UpdatedAt.IsZero() {
+		return cost.PRInfo{}, false
+	}
+	stored, ok := a.Store.PR(org, repo, prNum, a.measurement())
+	if !ok || !stored.UpdatedAt.Equal(pr.UpdatedAt) || stored.Info.Failed() {
+		return cost.PRInfo{}, false
+	}
+	if !a.Filter.excludesAll(stored.ExcludeJobs) {
+		// runs the earlier filter left out are kept now
+		return cost.PRInfo{}, false
+	}
+	prInfo := stored.Info
+	prInfo.URL = pr.URL
+	problems := &prProblems{warnings: prInfo.Warnings}
+	prInfo.Jobs = nil
+	for _, job := range stored.Info.Jobs {
+		if a.Filter.KeepJob(job.JobName) {
+			prInfo.Jobs = append(prInfo.Jobs, job)
+		}
+	}
+	for i := range prInfo.Jobs {
+		job := &prInfo.Jobs[i]
+		if job.Status == cost.StatusRunning || job.Status == cost.StatusFetchError {
+			return cost.PRInfo{}, false
+		}
+		if job.PricingMethod == cost.MethodMedianEstimate {
+			// the median depends on every PR of this run
+			return cost.PRInfo{}, false
+		}
+		if job.Platform != "" {
+			a.priceJob(job, problems)
+		}
+	}
+	prInfo.Warnings = problems.warnings
+	prInfo.ComputeTotals()
+	return prInfo, true
+}
+
+// measurement identifies the settings that decide the billable hours of a
+// run, which the rate card's hourly rates are then applied to. Stored runs
+// measured with other settings are measured again.
+func (a *PRCosts) measurement() string {
+	policy := a.UnfinishedPolicy
+	if policy == "" {
+		policy = cost.PolicyExclude
+	}
+	data, err := json.Marshal(struct {
+		Overhead         cost.OverheadModel
+		StepTimings      bool
+		MeasureOverhead  bool
+		UnfinishedPolicy string
+	}{a.RateCard.Overhead, a.StepTimings, a.MeasureOverhead, policy})
+	if err != nil {
+		panic(fmt.Sprintf("failed to marshal measurement settings: %v", err))
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// settledRun reports whether a job run and what was read about it can no
+// longer change, so it can be stored.
+func settledRun(run prow.JobRun, jobInfo cost.JobInfo) bool {
+	switch jobInfo.Status {
+	case cost.StatusComplete:
+		return true
+	case cost.StatusNoCluster:
+		return run.Finished()
+	}
+	return false
+}
+
+// priceJob sets the cost of a measured job run and its steps from the rate
+// card.
+func (a *PRCosts) priceJob(jobInfo *cost.JobInfo, problems *prProblems) {
+	rate, ok := a.RateCard.Price(jobInfo.Platform, jobInfo.JobName, jobInfo.StartTime)
+	if !ok {
+		problems.warnf("no %s rate on %s, cannot calculate costs of %s", jobInfo.Platform, jobInfo.StartTime.Format("2006-01-02"), jobInfo.JobURL)
+	}
+	jobInfo.ApplyRate(rate)
+}
+
+// jobPlatform returns the platform of the cluster profile recorded in the
+// run's prowjob.json, and the prowjob itself, falling back to the job name
+// when that can't be read.
+func (a *PRCosts) jobPlatform(ctx context.Context, org, repo string, prNum int, run prow.JobRun, problems *prProblems) (cost.Platform, *prow.ProwJob) {
+	prowJob, err := a.Prow.GetProwJob(ctx, org, repo, prNum, run)
+	if err != nil {
+		problems.warnf("platform of %s guessed from its job name: %v", run.URL, err)
+		return cost.PlatformFromJobName(run.JobName), nil
+	}
+	return cost.PlatformFromProfile(prowJob.ClusterProfile()), prowJob
+}
+
+// measureJob sets the status and billable hours of a job run: its run time
+// less the time spent before cloud resources are provisioned, or with
+// StepTimings the window the resources existed according to the step graph.
+// The time before provisioning comes from the rate card's overhead model, or
+// with MeasureOverhead from the step graph. Runs without a finish time are
+// priced according to UnfinishedPolicy.
+func (a *PRCosts) measureJob(ctx context.Context, org, repo string, prNum int, run prow.JobRun, jobInfo *cost.JobInfo, problems *prProblems) {
+	result, err := a.Prow.GetRunResult(ctx, org, repo, prNum, run)
+	started, finished := result.Started, result.Finished
+	if err != nil {
+		jobInfo.StatusReason = err.Error()
+		switch {
+		case started.IsZero() || !errors.Is(err, prow.ErrNotFound):
+			jobInfo.Status = cost.StatusFetchError
+			jobInfo.PricingMethod = cost.MethodExcluded
+			problems.warnf("timings of %s unreadable, run left unpriced: %v", run.URL, err)
+			return
+		case run.State == prow.StatePending:
+			jobInfo.Status = cost.StatusRunning
+		default:
+			jobInfo.Status = cost.StatusAbortedNoFinish
+		}
+		a.estimateUnfinished(run, started, jobInfo)
+		return
+	}
+	jobInfo.Status = cost.StatusComplete
+	jobInfo.Result = result.Result
+	if jobInfo.SHA == "" {
+		jobInfo.SHA = result.Revision
+	}
+
+	var steps []prow.Step
+	if a.StepTimings || a.MeasureOverhead {
+		steps, err = a.Prow.GetSteps(ctx, org, repo, prNum, run)
+		if err != nil {
+			problems.warnf("step graph of %s unreadable, priced with the overhead model: %v", run.URL, err)
+		}
+	}
+	if a.StepTimings {
+		if clusterStart, clusterEnd, ok := prow.ClusterWindow(steps, finished); ok {
+			jobInfo.ClusterStart = clusterStart
+			jobInfo.ClusterEnd = clusterEnd
+			jobInfo.Steps = stepsInWindow(steps, clusterStart, clusterEnd)
+			jobInfo.Duration = cost.BillableHours(clusterEnd.Sub(clusterStart).Hours(), 0)
+			jobInfo.OverheadHours = math.Round(clusterStart.Sub(started).Hours()*100) / 100
+			jobInfo.PricingMethod = cost.MethodMeasured
+			return
+		}
+	}
+
+	overhead, method := a.RateCard.Overhead.Overhead(jobInfo.Platform, run.JobName)
+	if a.MeasureOverhead {
+		if provisioned, ok := prow.ProvisionStart(steps); ok && provisioned.After(started) {
+			overhead, method = provisioned.Sub(started).Hours(), cost.MethodMeasured
+		}
+	}
+	jobInfo.Duration = cost.BillableHours(finished.Sub(started).Hours(), overhead)
+	jobInfo.OverheadHours = math.Round(overhead*100) / 100
+	jobInfo.PricingMethod = method
+}
+
+// estimateUnfinished prices a run that has a start but no finish time.
+func (a *PRCosts) estimateUnfinished(run prow.JobRun, started time.Time, jobInfo *cost.JobInfo) {
+	overhead, _ := a.RateCard.Overhead.Overhead(jobInfo.Platform, run.JobName)
+	jobInfo.OverheadHours = overhead
+	switch a.UnfinishedPolicy {
+	case cost.PolicyAbortTime:
+		end := run.FinishTime
+		if end.IsZero() && jobInfo.Status == cost.StatusRunning {
+			end = a.now()
+		}
+		if end.After(started) {
+			jobInfo.Duration = cost.BillableHours(end.Sub(started).Hours(), overhead)
+			jobInfo.PricingMethod = cost.MethodAbortTimeEstimate
+			return
+		}
+	case cost.PolicyMedian:
+		// filled in by applyMedianEstimates once every PR is processed
+		jobInfo.PricingMethod = cost.MethodMedianEstimate
+		return
+	}
+	jobInfo.PricingMethod = cost.MethodExcluded
+}
+
+func (a *PRCosts) now() time.Time {
+	if a.Now != nil {
+		return a.Now()
+	}
+	return time.Now()
+}
+
+// applyMedianEstimates prices the unfinished runs waiting for a median
+// estimate at the median billable hours of the complete runs of the same job.
+// Runs of jobs that never completed are excluded.
+func applyMedianEstimates(prInfos []cost.PRInfo, rateCard *cost.RateCard) {
+	durations := make(map[string][]float64)
+	for _, prInfo := range prInfos {
+		for _, job := range prInfo.Jobs {
+			if job.Status == cost.StatusComplete {
+				durations[job.JobName] = append(durations[job.JobName], job.Duration)
+			}
+		}
+	}
+
+	for i := range prInfos {
+		for j := range prInfos[i].Jobs {
+			job := &prInfos[i].Jobs[j]
+			if job.PricingMethod != cost.MethodMedianEstimate {
+				continue
+			}
+			if len(durations[job.JobName]) == 0 {
+				job.PricingMethod = cost.MethodExcluded
+				continue
+			}
+			rate, _ := rateCard.Price(job.Platform, job.JobName, job.StartTime)
+			job.Duration = cost.Median(durations[job.JobName])
+			job.ApplyRate(rate)
+		}
+		prInfos[i].ComputeTotals()
+	}
+}
+
+// stepsInWindow returns the hours each multi-stage substep ran within the
+// cluster window.
+func stepsInWindow(steps []prow.Step, start, end time.Time) []cost.StepInfo {
+	var stepInfos []cost.StepInfo
+	for _, step := range steps {
+		if step.Test == "" || step.StartedAt == nil || step.FinishedAt == nil {
+			continue
+		}
+		from, to := *step.StartedAt, *step.FinishedAt
+		if from.Before(start) {
+			from = start
+		}
+		if to.After(end) {
+			to = end
+		}
+		if !to.After(from) {
+			continue
+		}
+		stepInfos = append(stepInfos, cost.StepInfo{
+			Name:     step.Name,
+			Duration: math.Round(to.Sub(from).Hours()*100) / 100,
+		})
+	}
+	return stepInfos
+}
+
+// prProblems collects the errors and warnings hit while building a PRInfo.
+// Errors mean the PR's cost is incomplete, warnings that part of it is
+// estimated or left out. The runs of a PR are measured concurrently, so it
+// is locked.
+type prProblems struct {
+	mu       sync.Mutex
+	errors   []string
+	warnings []string
+}
+
+func (p *prProblems) errorf(format string, args ...interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.errors = append(p.errors, fmt.Sprintf(format, args...))
+}
+
+func (p *prProblems) warnf(format string, args ...interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	warning := fmt.Sprintf(format, args...)
+	for _, w := range p.warnings {
+		if w == warning {
+			return
+		}
+	}
+	p.warnings = append(p.warnings, warning)
+}
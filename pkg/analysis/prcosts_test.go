@@ -0,0 +1,120 @@
+package analysis
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"cix/pkg/cost"
+	"cix/pkg/github"
+	"cix/pkg/prow"
+	"cix/pkg/store"
+)
+
+func TestEstimateUnfinished(t *testing.T) {
+	started := time.Date(2023, 7, 3, 10, 0, 0, 0, time.UTC)
+	now := started.Add(3 * time.Hour)
+	tests := []struct {
+		name         string
+		policy       string
+		status       cost.JobStatus
+		finishTime   time.Time
+		wantDuration float64
+		wantMethod   string
+	}{
+		{name: "default excludes", status: cost.StatusRunning, wantMethod: cost.MethodExcluded},
+		{name: "exclude", policy: cost.PolicyExclude, status: cost.StatusAbortedNoFinish, finishTime: started.Add(time.Hour), wantMethod: cost.MethodExcluded},
+		{name: "running up to now", policy: cost.PolicyAbortTime, status: cost.StatusRunning, wantDuration: 2.5, wantMethod: cost.MethodAbortTimeEstimate},
+		{name: "aborted", policy: cost.PolicyAbortTime, status: cost.StatusAbortedNoFinish, finishTime: started.Add(2 * time.Hour), wantDuration: 1.5, wantMethod: cost.MethodAbortTimeEstimate},
+		{name: "aborted without abort time", policy: cost.PolicyAbortTime, status: cost.StatusAbortedNoFinish, wantMethod: cost.MethodExcluded},
+		{name: "median", policy: cost.PolicyMedian, status: cost.StatusRunning, wantMethod: cost.MethodMedianEstimate},
+	}
+	for _, tt := range tests {
+		a := &PRCosts{
+			RateCard:         cost.DefaultRateCard(),
+			UnfinishedPolicy: tt.policy,
+			Now:              func() time.Time { return now },
+		}
+		run := prow.JobRun{JobName: "e2e-aws", StartTime: started, FinishTime: tt.finishTime}
+		jobInfo := cost.JobInfo{Platform: cost.AWS, Status: tt.status}
+		a.estimateUnfinished(run, started, &jobInfo)
+		if jobInfo.Duration != tt.wantDuration || jobInfo.PricingMethod != tt.wantMethod {
+			t.Errorf("%s: estimated %v hours by %s, want %v by %s", tt.name, jobInfo.Duration, jobInfo.PricingMethod, tt.wantDuration, tt.wantMethod)
+		}
+	}
+}
+
+func TestApplyMedianEstimates(t *testing.T) {
+	started := time.Date(2023, 7, 3, 10, 0, 0, 0, time.UTC)
+	complete := func(hours float64) cost.JobInfo {
+		return cost.JobInfo{JobName: "e2e-aws", Platform: cost.AWS, Status: cost.StatusComplete, StartTime: started, Duration: hours, Cost: hours * 0.9}
+	}
+	prInfos := []cost.PRInfo{
+		{Jobs: []cost.JobInfo{complete(1), complete(2)}},
+		{Jobs: []cost.JobInfo{
+			complete(4),
+			{JobName: "e2e-aws", Platform: cost.AWS, Status: cost.StatusRunning, StartTime: started, PricingMethod: cost.MethodMedianEstimate},
+			{JobName: "e2e-gcp", Platform: cost.GCP, Status: cost.StatusRunning, StartTime: started, PricingMethod: cost.MethodMedianEstimate},
+		}},
+	}
+	applyMedianEstimates(prInfos, cost.DefaultRateCard())
+
+	estimated := prInfos[1].Jobs[1]
+	if estimated.Duration != 2 || estimated.Cost != 1.8 {
+		t.Errorf("median estimate is %v hours for $%v, want 2 hours for $1.8", estimated.Duration, estimated.Cost)
+	}
+	if never := prInfos[1].Jobs[2]; never.PricingMethod != cost.MethodExcluded {
+		t.Errorf("run of a job that never completed priced by %s, want %s", never.PricingMethod, cost.MethodExcluded)
+	}
+	if prInfos[1].TotalCost != 4*0.9+1.8 {
+		t.Errorf("PR total is %v after the estimates, want %v", prInfos[1].TotalCost, 4*0.9+1.8)
+	}
+}
+
+func TestStoredPR(t *testing.T) {
+	updated := time.Date(2023, 7, 3, 10, 0, 0, 0, time.UTC)
+	pr := github.PullRequest{URL: "https://github.com/openshift/ovn-kubernetes/pull/1", UpdatedAt: updated}
+	stored := cost.PRInfo{
+		Org:   "openshift",
+		Repo:  "ovn-kubernetes",
+		PRNum: 1,
+		Jobs: []cost.JobInfo{
+			{JobName: "e2e-aws", Platform: cost.AWS, Status: cost.StatusComplete, StartTime: updated, Duration: 1},
+			{JobName: "e2e-gcp", Platform: cost.GCP, Status: cost.StatusComplete, StartTime: updated, Duration: 2},
+		},
+	}
+	storedBy := &PRCosts{RateCard: cost.DefaultRateCard(), Filter: Filter{ExcludeJobs: []string{"upgrade"}}}
+
+	tests := []struct {
+		name     string
+		a        *PRCosts
+		wantJobs int
+		wantOK   bool
+	}{
+		{name: "same settings", a: &PRCosts{Filter: Filter{ExcludeJobs: []string{"upgrade"}}}, wantJobs: 2, wantOK: true},
+		{name: "narrower filter", a: &PRCosts{Filter: Filter{ExcludeJobs: []string{"gcp", "upgrade"}}}, wantJobs: 1, wantOK: true},
+		{name: "wider filter", a: &PRCosts{}, wantOK: false},
+		{name: "other filter", a: &PRCosts{Filter: Filter{ExcludeJobs: []string{"aws"}}}, wantOK: false},
+		{name: "step timings", a: &PRCosts{StepTimings: true, Filter: storedBy.Filter}, wantOK: false},
+		{name: "measured overhead", a: &PRCosts{MeasureOverhead: true, Filter: storedBy.Filter}, wantOK: false},
+		{name: "unfinished policy", a: &PRCosts{UnfinishedPolicy: cost.PolicyAbortTime, Filter: storedBy.Filter}, wantOK: false},
+		{name: "default unfinished policy spelled out", a: &PRCosts{UnfinishedPolicy: cost.PolicyExclude, Filter: storedBy.Filter}, wantJobs: 2, wantOK: true},
+	}
+	for _, tt := range tests {
+		s, err := store.Open(filepath.Join(t.TempDir(), "store.json"), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.PutPR(store.PR{UpdatedAt: updated, Measurement: storedBy.measurement(), ExcludeJobs: storedBy.Filter.ExcludeJobs, Info: stored})
+		tt.a.RateCard = cost.DefaultRateCard()
+		tt.a.Store = s
+		prInfo, ok := tt.a.storedPR(pr, "openshift", "ovn-kubernetes", 1)
+		if ok != tt.wantOK {
+			t.Errorf("%s: storedPR() reused the stored PR: %t, want %t", tt.name, ok, tt.wantOK)
+			continue
+		}
+		if ok && len(prInfo.Jobs) != tt.wantJobs {
+			t.Errorf("%s: storedPR() kept %d runs, want %d", tt.name, len(prInfo.Jobs), tt.wantJobs)
+		}
+	}
+}
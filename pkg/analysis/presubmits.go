@@ -0,0 +1,59 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"cix/pkg/prow"
+)
+
+// ResultsDepth is how many older job-history pages to look at (20 runs per page).
+const ResultsDepth = 2
+
+// AnalyzePresubmits computes the pass rate of every always-run e2e presubmit
+// of org/repo.
+func AnalyzePresubmits(ctx context.Context, prowClient *prow.Client, org, repo string, resultsDepth int) ([]prow.Presubmit, error) {
+	presubmits, err := prowClient.GetPresubmits(ctx, org, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []prow.Presubmit
+	for _, jobList := range presubmits.PresubmitJobs {
+		for _, job := range jobList {
+			// only care about e2e jobs that run on every PR
+			if strings.Contains(job.Name, "e2e") && job.AlwaysRun {
+				jobs = append(jobs, job)
+			}
+		}
+	}
+
+	for i, job := range jobs {
+		url := prowClient.JobHistoryURL(job.Name)
+		history, err := prowClient.GetJobHistory(ctx, url, resultsDepth)
+		if err != nil {
+			return nil, err
+		}
+
+		if history.UnexpectedStatusCount > 0 {
+			return nil, fmt.Errorf("did not parse proper number of expected jobs for %s: expected %d, but got %d unexpected statuses", url, (resultsDepth+1)*20, history.UnexpectedStatusCount)
+		}
+
+		totalJobCount := history.Total()
+		passRate := 0.0
+		if history.SuccessCount+history.FailureCount != 0 { // to avoid division by zero
+			passRate = float64(history.SuccessCount) / (float64(history.SuccessCount) + float64(history.FailureCount))
+		}
+
+		jobs[i].SuccessCount = history.SuccessCount
+		jobs[i].FailureCount = history.FailureCount
+		jobs[i].AbortedCount = history.AbortedCount
+		jobs[i].PendingCount = history.PendingCount
+		jobs[i].ErrorCount = history.ErrorCount
+		jobs[i].UnknownCount = history.UnknownCount
+		jobs[i].PassRate = passRate
+		jobs[i].TotalJobCount = totalJobCount
+	}
+	return jobs, nil
+}
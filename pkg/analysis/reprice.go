@@ -0,0 +1,68 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"cix/pkg/cost"
+	"cix/pkg/prow"
+)
+
+// Reprice prices the jobs of an existing report, and their steps, with
+// rateCard, using only what the report records. Jobs without a recorded
+// platform get it from their job name and jobs without a start time get it
+// from their build ID. It returns a warning for every job that could not be
+// priced.
+func Reprice(report *cost.Report, rateCard *cost.RateCard) []string {
+	var warnings []string
+	for i := range report.PRs {
+		prInfo := &report.PRs[i]
+		for j := range prInfo.Jobs {
+			job := &prInfo.Jobs[j]
+			// older reports list jobs that never had a cluster as empty entries
+			if job.JobURL == "" {
+				job.Status = cost.StatusNoCluster
+				continue
+			}
+			jobName, buildID := prow.ParseRunLink(job.JobURL)
+			if job.JobName == "" {
+				job.JobName = jobName
+			}
+			if job.Platform == "" {
+				job.Platform = cost.PlatformFromJobName(job.JobName)
+			}
+			// older reports mark runs whose timings could not be read with -1 hours
+			if job.Duration < 0 {
+				job.Duration = 0
+				job.Status = cost.StatusFetchError
+				job.PricingMethod = cost.MethodExcluded
+			}
+			if job.StartTime.IsZero() {
+				if started, ok := prow.BuildIDTime(buildID); ok {
+					job.StartTime = started
+				}
+			}
+			if job.Platform == "" {
+				job.ApplyRate(0)
+				job.Status = cost.StatusNoCluster
+				continue
+			}
+			if job.Status == "" {
+				job.Status = cost.StatusComplete
+			}
+			rate, ok := rateCard.Price(job.Platform, job.JobName, job.StartTime)
+			if !ok {
+				warnings = append(warnings, fmt.Sprintf("no %s rate on %s for %s", job.Platform, job.StartTime.Format("2006-01-02"), job.JobURL))
+			}
+			job.ApplyRate(rate)
+		}
+		prInfo.ComputeTotals()
+	}
+	sort.Slice(report.PRs, func(i, j int) bool {
+		return report.PRs[i].TotalCost > report.PRs[j].TotalCost
+	})
+	report.RateCard = rateCard
+	report.GeneratedAt = time.Now().UTC()
+	return warnings
+}
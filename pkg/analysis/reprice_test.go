@@ -0,0 +1,87 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"cix/pkg/cost"
+)
+
+const repriceRateCard = `
+version: "new"
+currency: USD
+rates:
+  - platform: aws
+    hourly: 2.0
+    effective_from: "2023-01-01"
+`
+
+func TestReprice(t *testing.T) {
+	rateCard, err := cost.ParseRateCard([]byte(repriceRateCard))
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := time.Date(2023, 7, 3, 10, 0, 0, 0, time.UTC)
+	report := &cost.Report{
+		PRs: []cost.PRInfo{{
+			Org:   "openshift",
+			Repo:  "ovn-kubernetes",
+			PRNum: 1700,
+			Jobs: []cost.JobInfo{
+				{
+					JobURL:    "https://prow.ci.openshift.org/view/gs/test-platform-results/pr-logs/pull/openshift_ovn-kubernetes/1700/pull-ci-openshift-ovn-kubernetes-master-e2e-aws-ovn/1676012345678901248",
+					JobName:   "pull-ci-openshift-ovn-kubernetes-master-e2e-aws-ovn",
+					Platform:  cost.AWS,
+					Status:    cost.StatusComplete,
+					StartTime: started,
+					Duration:  1.5,
+					Steps: []cost.StepInfo{
+						{Name: "ipi-install-install", Duration: 0.5, Cost: 0.45},
+						{Name: "e2e-aws-ovn-test", Duration: 1.0, Cost: 0.9},
+					},
+					Cost: 1.35,
+				},
+				// older reports mark unreadable runs with -1 hours and
+				// record neither platform nor start time
+				{
+					JobURL:   "https://prow.ci.openshift.org/view/gs/origin-ci-test/pr-logs/pull/openshift_ovn-kubernetes/1700/pull-ci-openshift-ovn-kubernetes-master-e2e-aws-ovn-upgrade/1676012345678901249",
+					Duration: -1,
+				},
+				{},
+			},
+		}},
+	}
+
+	if warnings := Reprice(report, rateCard); len(warnings) != 0 {
+		t.Errorf("Reprice() warned: %v", warnings)
+	}
+
+	prInfo := report.PRs[0]
+	job := prInfo.Jobs[0]
+	if job.Cost != 3.0 {
+		t.Errorf("job cost = %v, want 3.0", job.Cost)
+	}
+	stepCost := 0.0
+	for _, step := range job.Steps {
+		stepCost += step.Cost
+	}
+	if math.Abs(stepCost-job.Cost) > 1e-9 {
+		t.Errorf("step costs add up to %v, want the job cost %v", stepCost, job.Cost)
+	}
+
+	old := prInfo.Jobs[1]
+	if old.JobName != "pull-ci-openshift-ovn-kubernetes-master-e2e-aws-ovn-upgrade" || old.Platform != cost.AWS {
+		t.Errorf("old run has job %q on %q, want it derived from its URL", old.JobName, old.Platform)
+	}
+	if old.Status != cost.StatusFetchError || old.Duration != 0 || old.Cost != 0 || old.StartTime.IsZero() {
+		t.Errorf("old unreadable run repriced as %+v", old)
+	}
+	if prInfo.Jobs[2].Status != cost.StatusNoCluster {
+		t.Errorf("empty run has status %q, want %q", prInfo.Jobs[2].Status, cost.StatusNoCluster)
+	}
+
+	if prInfo.TotalCost != 3.0 || report.RateCard != rateCard {
+		t.Errorf("repriced report has total %v and rate card %v", prInfo.TotalCost, report.RateCard)
+	}
+}
@@ -0,0 +1,176 @@
+// Package cassette records the HTTP exchanges of a run into a directory and
+// replays them later without a network, so a report can be reproduced
+// exactly and parsing problems can be debugged from the captured data.
+package cassette
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Mode tells whether a Cassette records or replays.
+type Mode int
+
+const (
+	Record Mode = iota
+	Replay
+)
+
+// Exchange is a recorded request and its response. Request headers are not
+// recorded, so credentials never end up in a cassette.
+type Exchange struct {
+	Method      string
+	URL         string
+	RequestBody string `json:",omitempty"`
+	StatusCode  int
+	Header      http.Header
+	Body        []byte
+}
+
+// Cassette is an http.RoundTripper that records exchanges into Dir or
+// replays them from it. In replay mode a request that was not recorded fails,
+// and is remembered so the run can be failed as a whole.
+type Cassette struct {
+	Dir  string
+	Mode Mode
+	// the transport recorded requests are sent with, http.DefaultTransport
+	// when nil
+	Transport http.RoundTripper
+
+	mu      sync.Mutex
+	missing map[string]bool
+}
+
+// New returns a Cassette recording into or replaying from dir.
+func New(dir string, mode Mode) (*Cassette, error) {
+	switch mode {
+	case Record:
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create cassette directory: %v", err)
+		}
+	case Replay:
+		if _, err := os.Stat(dir); err != nil {
+			return nil, fmt.Errorf("failed to open cassette: %v", err)
+		}
+	}
+	return &Cassette{Dir: dir, Mode: mode, missing: make(map[string]bool)}, nil
+}
+
+// Client returns an http.Client that sends its requests through the cassette.
+func (c *Cassette) Client() *http.Client {
+	return &http.Client{Transport: c}
+}
+
+// Missing returns the requests that were replayed without a recording,
+// sorted.
+func (c *Cassette) Missing() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	missing := make([]string, 0, len(c.missing))
+	for request := range c.missing {
+		missing = append(missing, request)
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// RoundTrip records or replays a single exchange.
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		reqBody, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+	path := filepath.Join(c.Dir, key(req.Method, req.URL.String(), reqBody)+".json")
+
+	if c.Mode == Replay {
+		return c.replay(req, path)
+	}
+
+	transport := c.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	data, err := json.MarshalIndent(Exchange{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		RequestBody: string(reqBody),
+		StatusCode:  resp.StatusCode,
+		Header:      resp.Header,
+		Body:        body,
+	}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to record %s %s: %v", req.Method, req.URL, err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to record %s %s: %v", req.Method, req.URL, err)
+	}
+	return resp, nil
+}
+
+func (c *Cassette) replay(req *http.Request, path string) (*http.Response, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		request := req.Method + " " + req.URL.String()
+		c.mu.Lock()
+		c.missing[request] = true
+		c.mu.Unlock()
+		log.Printf("Cassette %s has no recording of %s", c.Dir, request)
+		return nil, fmt.Errorf("cassette %s has no recording of %s", c.Dir, request)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to replay %s %s: %v", req.Method, req.URL, err)
+	}
+
+	var exchange Exchange
+	if err := json.Unmarshal(data, &exchange); err != nil {
+		return nil, fmt.Errorf("failed to decode recording %s: %v", path, err)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", exchange.StatusCode, http.StatusText(exchange.StatusCode)),
+		StatusCode:    exchange.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        exchange.Header,
+		Body:          io.NopCloser(bytes.NewReader(exchange.Body)),
+		ContentLength: int64(len(exchange.Body)),
+		Request:       req,
+	}, nil
+}
+
+// key identifies a request by method, URL and body, such as the query of a
+// GraphQL request.
+func key(method, url string, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, method+" "+url+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,142 @@
+package cassette
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// roundTripFunc is an http.RoundTripper calling itself.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// echo answers every request with its method, URL and body.
+var echo = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		body, _ = io.ReadAll(req.Body)
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"X-Ratelimit-Remaining": {"4999"}},
+		Body:       io.NopCloser(strings.NewReader(req.Method + " " + req.URL.String() + " " + string(body))),
+	}, nil
+})
+
+func send(t *testing.T, c *Cassette, method, url, body string) (string, error) {
+	t.Helper()
+	var reqBody io.Reader
+	if body != "" {
+		reqBody = strings.NewReader(body)
+	}
+	req, err := http.NewRequest(method, url, reqBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "bearer secret-token")
+	resp, err := c.Client().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	return string(data), err
+}
+
+func TestRecordReplay(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cassette")
+	recorder, err := New(dir, Record)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	recorder.Transport = echo
+	const graphql = "https://api.github.com/graphql"
+	requests := []struct{ method, url, body string }{
+		{http.MethodGet, "https://api.github.com/repos/o/r/issues/1/comments?per_page=100", ""},
+		{http.MethodGet, "https://api.github.com/repos/o/r/issues/1/comments?per_page=100&page=2", ""},
+		// two queries of the same endpoint are kept apart by their bodies
+		{http.MethodPost, graphql, `{"query":"q1"}`},
+		{http.MethodPost, graphql, `{"query":"q2"}`},
+		{http.MethodDelete, graphql, `{"query":"q1"}`},
+	}
+	want := make([]string, len(requests))
+	for i, r := range requests {
+		if want[i], err = send(t, recorder, r.method, r.url, r.body); err != nil {
+			t.Fatalf("recording %s %s failed: %v", r.method, r.url, err)
+		}
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != len(requests) {
+		t.Errorf("cassette holds %d recordings, want %d", len(files), len(requests))
+	}
+	for _, f := range files {
+		data, err := os.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(data), "secret-token") || strings.Contains(string(data), "Authorization") {
+			t.Errorf("recording %s holds the request's Authorization header", f.Name())
+		}
+	}
+
+	player, err := New(dir, Replay)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	player.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		t.Errorf("replay sent %s %s", req.Method, req.URL)
+		return nil, io.EOF
+	})
+	for i, r := range requests {
+		got, err := send(t, player, r.method, r.url, r.body)
+		if err != nil || got != want[i] {
+			t.Errorf("replay of %s %s %s = %q, %v, want %q", r.method, r.url, r.body, got, err, want[i])
+		}
+	}
+	if missing := player.Missing(); len(missing) != 0 {
+		t.Errorf("Missing() = %v after replaying recorded requests, want none", missing)
+	}
+}
+
+func TestReplayMissing(t *testing.T) {
+	c, err := New(t.TempDir(), Replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, url := range []string{"https://b.example.com/", "https://a.example.com/", "https://b.example.com/"} {
+		if _, err := send(t, c, http.MethodGet, url, ""); err == nil {
+			t.Errorf("replay of %s without a recording succeeded", url)
+		}
+	}
+	if _, err := send(t, c, http.MethodPost, "https://a.example.com/", "q"); err == nil {
+		t.Errorf("replay of a POST without a recording succeeded")
+	}
+	want := []string{"GET https://a.example.com/", "GET https://b.example.com/", "POST https://a.example.com/"}
+	if got := c.Missing(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Missing() = %v, want %v", got, want)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(filepath.Join(t.TempDir(), "none"), Replay); err == nil {
+		t.Errorf("New() replaying a missing directory succeeded")
+	}
+	dir := filepath.Join(t.TempDir(), "a", "b")
+	if _, err := New(dir, Record); err != nil {
+		t.Fatalf("New() recording into a new directory failed: %v", err)
+	}
+	if _, err := os.Stat(dir); err != nil {
+		t.Errorf("New() did not create the directory: %v", err)
+	}
+}
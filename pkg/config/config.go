@@ -0,0 +1,220 @@
+// Package config reads the file describing a multi-repo cost run: the period,
+// the repos and their overrides, and where the reports go. For example:
+//
+//	output_dir: reports
+//	periods: [2023-Q2, 2023-Q3]
+//	filters:
+//	  merged_only: true
+//	repos:
+//	- org: openshift
+//	  repo: ovn-kubernetes
+//	  name: ovnk
+//	- org: openshift
+//	  repo: cluster-network-operator
+//	  name: cno
+//	  filters:
+//	    exclude_jobs: [okd]
+//
+// writes reports/2023-Q3_ovnk.json, reports/2023-Q3_cno.json and the
+// comparison of both in reports/2023-Q3_combined.json, the same for 2023-Q2,
+// and how each repo changed between the periods in
+// reports/2023-Q2_to_2023-Q3_comparison.json. A single window can be given
+// with start and end dates instead, named by period.
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"cix/pkg/analysis"
+	"cix/pkg/period"
+)
+
+// Config is a multi-repo cost run. Settings at the top level apply to every
+// repo that does not override them.
+type Config struct {
+	// reports are written here, created when missing
+	OutputDir string `yaml:"output_dir"`
+	// a period PRs are looked at for, as MM-DD-YYYY dates, and the name
+	// used for it in report file names
+	Start  string `yaml:"start,omitempty"`
+	End    string `yaml:"end,omitempty"`
+	Period string `yaml:"period,omitempty"`
+	// named periods PRs are looked at for, such as 2023-Q3, 2023-08,
+	// last-30d or a sprint of the Sprints calendar
+	Periods []string `yaml:"periods,omitempty"`
+	Sprints string   `yaml:"sprints,omitempty"`
+
+	ProwProfile string           `yaml:"prow_profile,omitempty"`
+	RateCard    string           `yaml:"rate_card,omitempty"`
+	Filter      *analysis.Filter `yaml:"filters,omitempty"`
+	Repos       []Repo           `yaml:"repos"`
+
+	// the directory relative paths are resolved against
+	dir string
+}
+
+// Repo is one repo of a run, with the settings it overrides.
+type Repo struct {
+	Org  string `yaml:"org"`
+	Repo string `yaml:"repo"`
+	// used in file names instead of org_repo, such as ovnk
+	Name        string           `yaml:"name,omitempty"`
+	ProwProfile string           `yaml:"prow_profile,omitempty"`
+	RateCard    string           `yaml:"rate_card,omitempty"`
+	Filter      *analysis.Filter `yaml:"filters,omitempty"`
+}
+
+// Load reads and checks the config file at path.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %v", err)
+	}
+	var c Config
+	if err := yaml.UnmarshalStrict(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %v", path, err)
+	}
+	c.dir = filepath.Dir(path)
+
+	if (c.Start == "") != (c.End == "") {
+		return nil, fmt.Errorf("config %s: start and end go together", path)
+	}
+	if c.Start == "" && len(c.Periods) == 0 {
+		return nil, fmt.Errorf("config %s: start and end or periods are required", path)
+	}
+	if len(c.Repos) == 0 {
+		return nil, fmt.Errorf("config %s lists no repos", path)
+	}
+	names := make(map[string]bool)
+	for _, r := range c.Repos {
+		if r.Org == "" || r.Repo == "" {
+			return nil, fmt.Errorf("config %s: every repo needs an org and a repo", path)
+		}
+		if names[r.FileName()] {
+			return nil, fmt.Errorf("config %s: more than one repo is named %s", path, r.FileName())
+		}
+		names[r.FileName()] = true
+	}
+	if c.OutputDir == "" {
+		c.OutputDir = "."
+	}
+	return &c, nil
+}
+
+// GetPeriods returns the periods of the run in the order they are given, the
+// start to end one first. Periods relative to today are resolved against now.
+func (c *Config) GetPeriods(now time.Time) ([]period.Period, error) {
+	var calendar period.Calendar
+	if c.Sprints != "" {
+		var err error
+		calendar, err = period.LoadCalendar(c.Path(c.Sprints))
+		if err != nil {
+			return nil, err
+		}
+	}
+	periods, err := period.ParseAll(c.Periods, now, calendar)
+	if err != nil {
+		return nil, err
+	}
+	if c.Start == "" {
+		return periods, nil
+	}
+
+	start, err := period.ParseDate(c.Start)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse start date: %v", err)
+	}
+	end, err := period.ParseDate(c.End)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse end date: %v", err)
+	}
+	window := period.Range(start, end)
+	if c.Period != "" {
+		window.Name = c.Period
+	}
+	for _, p := range periods {
+		if p.Name == window.Name {
+			return nil, fmt.Errorf("period %s is given more than once", p.Name)
+		}
+	}
+	return append([]period.Period{window}, periods...), nil
+}
+
+// Path resolves a path from the config file against the file's directory.
+func (c *Config) Path(path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(c.dir, path)
+}
+
+// ProwProfileFor returns the Prow profile of a repo, empty to pick it by repo.
+func (c *Config) ProwProfileFor(r Repo) string {
+	if r.ProwProfile != "" {
+		return r.ProwProfile
+	}
+	return c.ProwProfile
+}
+
+// RateCardFor returns the rate card file of a repo, empty for the built-in
+// one.
+func (c *Config) RateCardFor(r Repo) string {
+	if r.RateCard != "" {
+		return c.Path(r.RateCard)
+	}
+	return c.Path(c.RateCard)
+}
+
+// FilterFor returns the filter of a repo. A repo's filters replace the
+// top-level ones as a whole.
+func (c *Config) FilterFor(r Repo) analysis.Filter {
+	if r.Filter != nil {
+		return *r.Filter
+	}
+	if c.Filter != nil {
+		return *c.Filter
+	}
+	return analysis.Filter{}
+}
+
+// FileName returns the name of a repo used in report file names.
+func (r Repo) FileName() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return r.Org + "_" + r.Repo
+}
+
+// ReportPath returns where the report of a repo for a period is written:
+// <output_dir>/<period>_<repo>.json.
+func (c *Config) ReportPath(period string, r Repo) string {
+	return filepath.Join(c.Path(c.OutputDir), fileSafe(period)+"_"+fileSafe(r.FileName())+".json")
+}
+
+// CombinedPath returns where the combined report of a period is written:
+// <output_dir>/<period>_combined.json.
+func (c *Config) CombinedPath(period string) string {
+	return filepath.Join(c.Path(c.OutputDir), fileSafe(period)+"_combined.json")
+}
+
+// ComparisonPath returns where the comparison of periods is written:
+// <output_dir>/<first>_to_<last>_comparison.json.
+func (c *Config) ComparisonPath(periods []period.Period) string {
+	name := periods[0].Name + "_to_" + periods[len(periods)-1].Name
+	return filepath.Join(c.Path(c.OutputDir), fileSafe(name)+"_comparison.json")
+}
+
+func fileSafe(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ' ' || r == ':' {
+			return '_'
+		}
+		return r
+	}, name)
+}
@@ -0,0 +1,209 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"cix/pkg/analysis"
+	"cix/pkg/period"
+)
+
+func writeConfig(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	repos := "repos:\n- org: openshift\n  repo: ovn-kubernetes\n"
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{name: "start and end", data: "start: 07-01-2023\nend: 07-31-2023\n" + repos},
+		{name: "periods", data: "periods: [2023-Q3]\n" + repos},
+		{name: "start without end", data: "start: 07-01-2023\n" + repos, wantErr: true},
+		{name: "end without start", data: "end: 07-31-2023\n" + repos, wantErr: true},
+		{name: "no period", data: repos, wantErr: true},
+		{name: "no repos", data: "periods: [2023-Q3]\n", wantErr: true},
+		{name: "repo without org", data: "periods: [2023-Q3]\nrepos:\n- repo: ovn-kubernetes\n", wantErr: true},
+		{
+			name:    "same name twice",
+			data:    "periods: [2023-Q3]\nrepos:\n- org: openshift\n  repo: ovn-kubernetes\n  name: ovnk\n- org: ovn-org\n  repo: ovn-kubernetes\n  name: ovnk\n",
+			wantErr: true,
+		},
+		{name: "unknown field", data: "periods: [2023-Q3]\noutput: reports\n" + repos, wantErr: true},
+		{name: "not yaml", data: "periods: [2023-Q3\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		c, err := Load(writeConfig(t, tt.data))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Load() error = %v, want error %t", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && c.OutputDir != "." {
+			t.Errorf("%s: OutputDir = %q, want the default .", tt.name, c.OutputDir)
+		}
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Errorf("Load() of a missing file succeeded")
+	}
+}
+
+func TestRepoOverrides(t *testing.T) {
+	path := writeConfig(t, `periods: [2023-Q3]
+prow_profile: openshift
+rate_card: rates.yaml
+filters:
+  merged_only: true
+repos:
+- org: openshift
+  repo: ovn-kubernetes
+- org: ovn-org
+  repo: ovn-kubernetes
+  prow_profile: upstream
+  rate_card: /etc/cix/upstream-rates.yaml
+  filters:
+    exclude_jobs: [okd]
+`)
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	dir := filepath.Dir(path)
+	tests := []struct {
+		repo        Repo
+		wantProfile string
+		wantCard    string
+		wantFilter  analysis.Filter
+	}{
+		{
+			repo:        c.Repos[0],
+			wantProfile: "openshift",
+			wantCard:    filepath.Join(dir, "rates.yaml"),
+			wantFilter:  analysis.Filter{MergedOnly: true},
+		},
+		// a repo's filters replace the top-level ones as a whole
+		{
+			repo:        c.Repos[1],
+			wantProfile: "upstream",
+			wantCard:    "/etc/cix/upstream-rates.yaml",
+			wantFilter:  analysis.Filter{ExcludeJobs: []string{"okd"}},
+		},
+	}
+	for _, tt := range tests {
+		name := tt.repo.Org + "/" + tt.repo.Repo
+		if got := c.ProwProfileFor(tt.repo); got != tt.wantProfile {
+			t.Errorf("%s: ProwProfileFor() = %q, want %q", name, got, tt.wantProfile)
+		}
+		if got := c.RateCardFor(tt.repo); got != tt.wantCard {
+			t.Errorf("%s: RateCardFor() = %q, want %q", name, got, tt.wantCard)
+		}
+		if got := c.FilterFor(tt.repo); !reflect.DeepEqual(got, tt.wantFilter) {
+			t.Errorf("%s: FilterFor() = %+v, want %+v", name, got, tt.wantFilter)
+		}
+	}
+
+	// -prow-profile replaces the default, not a repo's own profile
+	c.ProwProfile = "flag"
+	if got := c.ProwProfileFor(c.Repos[0]); got != "flag" {
+		t.Errorf("ProwProfileFor() with the default replaced = %q, want flag", got)
+	}
+	if got := c.ProwProfileFor(c.Repos[1]); got != "upstream" {
+		t.Errorf("ProwProfileFor() of a repo with its own profile = %q, want upstream", got)
+	}
+
+	// without any settings the built-in defaults are used
+	bare := &Config{dir: dir}
+	if profile, card, filter := bare.ProwProfileFor(Repo{}), bare.RateCardFor(Repo{}), bare.FilterFor(Repo{}); profile != "" || card != "" || !reflect.DeepEqual(filter, analysis.Filter{}) {
+		t.Errorf("empty config gives profile %q, rate card %q and filter %+v, want none", profile, card, filter)
+	}
+}
+
+func TestPaths(t *testing.T) {
+	c := &Config{OutputDir: "reports", dir: "/etc/cix"}
+	ovnk := Repo{Org: "openshift", Repo: "ovn-kubernetes", Name: "ovnk"}
+	cno := Repo{Org: "openshift", Repo: "cluster-network-operator"}
+	periods := []period.Period{{Name: "2023-Q2"}, {Name: "sprint 241"}, {Name: "2023-Q3"}}
+	tests := []struct {
+		got, want string
+	}{
+		{c.ReportPath("2023-Q3", ovnk), "/etc/cix/reports/2023-Q3_ovnk.json"},
+		{c.ReportPath("2023-Q3", cno), "/etc/cix/reports/2023-Q3_openshift_cluster-network-operator.json"},
+		{c.ReportPath("sprint 241", ovnk), "/etc/cix/reports/sprint_241_ovnk.json"},
+		{c.CombinedPath("2023-Q3"), "/etc/cix/reports/2023-Q3_combined.json"},
+		{c.CombinedPath("team/net:241"), "/etc/cix/reports/team_net_241_combined.json"},
+		{c.ComparisonPath(periods), "/etc/cix/reports/2023-Q2_to_2023-Q3_comparison.json"},
+		{c.ComparisonPath(periods[1:2]), "/etc/cix/reports/sprint_241_to_sprint_241_comparison.json"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("got path %s, want %s", tt.got, tt.want)
+		}
+	}
+
+	// an absolute output directory is kept
+	c.OutputDir = "/var/reports"
+	if got := c.CombinedPath("2023-Q3"); got != "/var/reports/2023-Q3_combined.json" {
+		t.Errorf("CombinedPath() = %s, want it under /var/reports", got)
+	}
+}
+
+func TestFileSafe(t *testing.T) {
+	tests := map[string]string{
+		"2023-Q3":                  "2023-Q3",
+		"2023-07-01_2023-07-15":    "2023-07-01_2023-07-15",
+		"team/sprint 241":          "team_sprint_241",
+		`a\b:c`:                    "a_b_c",
+		"openshift_ovn-kubernetes": "openshift_ovn-kubernetes",
+	}
+	for in, want := range tests {
+		if got := fileSafe(in); got != want {
+			t.Errorf("fileSafe(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestGetPeriods(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "sprints.yaml"), []byte("sprints:\n- name: sprint-241\n  start: 08-07-2023\n  end: 08-25-2023\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2023, 8, 15, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		config    Config
+		wantNames []string
+		wantErr   bool
+	}{
+		{name: "window first", config: Config{Start: "07-01-2023", End: "07-31-2023", Periods: []string{"2023-Q2"}}, wantNames: []string{"2023-07-01_2023-07-31", "2023-Q2"}},
+		{name: "named window", config: Config{Start: "07-01-2023", End: "07-31-2023", Period: "july"}, wantNames: []string{"july"}},
+		{name: "sprints", config: Config{Periods: []string{"sprint-241", "last-7d"}, Sprints: "sprints.yaml"}, wantNames: []string{"sprint-241", "last-7d"}},
+		{name: "window named like a period", config: Config{Start: "07-01-2023", End: "07-31-2023", Period: "2023-07", Periods: []string{"2023-07"}}, wantErr: true},
+		{name: "missing calendar", config: Config{Periods: []string{"2023-Q3"}, Sprints: "none.yaml"}, wantErr: true},
+		{name: "bad start", config: Config{Start: "2023-07-01", End: "07-31-2023"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		tt.config.dir = dir
+		periods, err := tt.config.GetPeriods(now)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: GetPeriods() error = %v, want error %t", tt.name, err, tt.wantErr)
+			continue
+		}
+		var names []string
+		for _, p := range periods {
+			names = append(names, p.Name)
+		}
+		if !reflect.DeepEqual(names, tt.wantNames) {
+			t.Errorf("%s: GetPeriods() = %v, want %v", tt.name, names, tt.wantNames)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package cost
+
+import (
+	"time"
+
+	"cix/pkg/stats"
+)
+
+// Metrics compared between periods.
+const (
+	MetricTotalCost      = "total_cost"
+	MetricMeanPRCost     = "mean_pr_cost"
+	MetricMedianPRCost   = "median_pr_cost"
+	MetricRetestsPerPR   = "retests_per_pr"
+	MetricMedianLifespan = "median_lifespan"
+)
+
+// Tests used to compare periods. Means are compared with Welch's t-test on
+// per-PR values, medians with the Mann-Whitney U test. Sums are not tested:
+// a test on per-PR values tells whether their mean moved, while a sum also
+// moves with the number of PRs.
+const (
+	TestWelch       = "welch-t"
+	TestMannWhitney = "mann-whitney-u"
+)
+
+// DefaultAlpha is the p-value below which a change counts as significant.
+const DefaultAlpha = 0.05
+
+// PeriodPRs are the PRs of a repo over one period.
+type PeriodPRs struct {
+	Period string
+	Start  time.Time
+	End    time.Time
+	// the report file the PRs were read from
+	Report  string
+	PRs     []PRInfo
+	Partial bool
+}
+
+// PeriodSummary is the summary of a repo over one period.
+type PeriodSummary struct {
+	Period string
+	Start  time.Time
+	End    time.Time
+	Summary
+}
+
+// MetricChange is how a metric moved from one period to the next. Test is
+// empty for metrics that are not tested, and Tested is false for those and
+// when a period has too few PRs to test; the change is then never
+// significant.
+type MetricChange struct {
+	Metric string
+	Before float64
+	After  float64
+	Change float64
+	// change relative to Before, 0 when Before is 0
+	ChangePct   float64
+	Test        string
+	Tested      bool
+	PValue      float64
+	Significant bool
+}
+
+// PeriodChange holds the changes of every metric from one period to the next.
+type PeriodChange struct {
+	From    string
+	To      string
+	Metrics []MetricChange
+}
+
+// Comparison compares a repo over consecutive periods.
+type Comparison struct {
+	Org     string
+	Repo    string
+	Alpha   float64
+	Periods []PeriodSummary
+	Changes []PeriodChange
+}
+
+// ComparisonReport is what a multi-period run writes next to its per-period
+// reports.
+type ComparisonReport struct {
+	GeneratedAt time.Time
+	Repos       []Comparison
+	// set when any period's report is partial
+	Partial bool `json:",omitempty"`
+}
+
+// ComparePeriods summarizes the PRs of org/repo for every period and tests
+// the change of each metric from one period to the next.
+func ComparePeriods(org, repo string, periods []PeriodPRs, alpha float64) Comparison {
+	if alpha <= 0 {
+		alpha = DefaultAlpha
+	}
+	c := Comparison{Org: org, Repo: repo, Alpha: alpha}
+	for i, p := range periods {
+		summary := Summarize(org, repo, p.PRs)
+		summary.Report = p.Report
+		summary.Partial = p.Partial
+		c.Periods = append(c.Periods, PeriodSummary{Period: p.Period, Start: p.Start, End: p.End, Summary: summary})
+		if i == 0 {
+			continue
+		}
+		before, after := c.Periods[i-1].Summary, summary
+		prev := periods[i-1].PRs
+		c.Changes = append(c.Changes, PeriodChange{
+			From: periods[i-1].Period,
+			To:   p.Period,
+			Metrics: []MetricChange{
+				metricChange(MetricTotalCost, before.TotalCost, after.TotalCost, "", nil, nil, alpha),
+				metricChange(MetricMeanPRCost, before.MeanPRCost, after.MeanPRCost, TestWelch,
+					prValues(prev, prCost), prValues(p.PRs, prCost), alpha),
+				metricChange(MetricMedianPRCost, before.MedianPRCost, after.MedianPRCost, TestMannWhitney,
+					prValues(prev, prCost), prValues(p.PRs, prCost), alpha),
+				metricChange(MetricRetestsPerPR, before.RetestsPerPR, after.RetestsPerPR, TestWelch,
+					prValues(prev, prRetests), prValues(p.PRs, prRetests), alpha),
+				metricChange(MetricMedianLifespan, before.MedianLifespan, after.MedianLifespan, TestMannWhitney,
+					prValues(prev, prLifespan), prValues(p.PRs, prLifespan), alpha),
+			},
+		})
+	}
+	return c
+}
+
+func metricChange(metric string, before, after float64, test string, a, b []float64, alpha float64) MetricChange {
+	m := MetricChange{
+		Metric: metric,
+		Before: before,
+		After:  after,
+		Change: after - before,
+		Test:   test,
+	}
+	if before != 0 {
+		m.ChangePct = (after - before) / before * 100
+	}
+	switch test {
+	case TestWelch:
+		m.PValue, m.Tested = stats.WelchTTest(a, b)
+	case TestMannWhitney:
+		m.PValue, m.Tested = stats.MannWhitneyU(a, b)
+	}
+	m.Significant = m.Tested && m.PValue < alpha
+	return m
+}
+
+func prValues(prInfos []PRInfo, value func(PRInfo) float64) []float64 {
+	values := make([]float64, len(prInfos))
+	for i, prInfo := range prInfos {
+		values[i] = value(prInfo)
+	}
+	return values
+}
+
+func prCost(prInfo PRInfo) float64     { return prInfo.TotalCost }
+func prRetests(prInfo PRInfo) float64  { return float64(prInfo.PRRetestCount) }
+func prLifespan(prInfo PRInfo) float64 { return prInfo.PRLifeSpan }
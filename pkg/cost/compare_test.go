@@ -0,0 +1,40 @@
+package cost
+
+import "testing"
+
+func TestComparePeriods(t *testing.T) {
+	prs := func(costs ...float64) []PRInfo {
+		var prInfos []PRInfo
+		for _, c := range costs {
+			prInfos = append(prInfos, PRInfo{TotalCost: c, PRLifeSpan: 1})
+		}
+		return prInfos
+	}
+	c := ComparePeriods("openshift", "ovn-kubernetes", []PeriodPRs{
+		{Period: "2023-Q2", PRs: prs(1, 2, 3, 4)},
+		// more PRs of about the same cost
+		{Period: "2023-Q3", PRs: prs(1, 2, 3, 4, 1, 2, 3, 4)},
+	}, 0)
+	if c.Alpha != DefaultAlpha || len(c.Changes) != 1 {
+		t.Fatalf("ComparePeriods() has alpha %v and %d changes, want %v and 1", c.Alpha, len(c.Changes), DefaultAlpha)
+	}
+	metrics := make(map[string]MetricChange)
+	for _, m := range c.Changes[0].Metrics {
+		metrics[m.Metric] = m
+	}
+
+	total := metrics[MetricTotalCost]
+	if total.Change != 10 || total.ChangePct != 100 {
+		t.Errorf("total cost changed by %v (%v%%), want 10 (100%%)", total.Change, total.ChangePct)
+	}
+	if total.Test != "" || total.Tested || total.Significant {
+		t.Errorf("total cost tested with %q: %t, want untested", total.Test, total.Tested)
+	}
+	mean := metrics[MetricMeanPRCost]
+	if mean.Test != TestWelch || !mean.Tested || mean.PValue != 1 || mean.Significant {
+		t.Errorf("mean PR cost tested with %q: %t, p=%v, want %q with p=1", mean.Test, mean.Tested, mean.PValue, TestWelch)
+	}
+	if median := metrics[MetricMedianPRCost]; median.Test != TestMannWhitney || !median.Tested {
+		t.Errorf("median PR cost tested with %q: %t, want %q", median.Test, median.Tested, TestMannWhitney)
+	}
+}
@@ -0,0 +1,138 @@
+// Package cost holds the per-PR cloud cost model.
+package cost
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+type JobInfo struct {
+	JobURL   string
+	JobName  string    `json:",omitempty"`
+	Platform Platform  `json:",omitempty"`
+	Status   JobStatus `json:",omitempty"`
+	// why the run's timings could not be read
+	StatusReason string `json:",omitempty"`
+	// SUCCESS, FAILURE, ABORTED or ERROR, as recorded in finished.json
+	Result string `json:",omitempty"`
+	// the PR commit the run tested
+	SHA string `json:",omitempty"`
+	// when the run started, which picks the rate it is priced with
+	StartTime time.Time `json:",omitempty"`
+	// billable hours, the run time less OverheadHours
+	Duration      float64
+	OverheadHours float64 `json:",omitempty"`
+	// how the overhead was found, see the Method constants
+	PricingMethod string `json:",omitempty"`
+	// when step timings were read, the window the run's cloud resources
+	// existed and the multi-stage steps that ran in it
+	ClusterStart time.Time  `json:",omitempty"`
+	ClusterEnd   time.Time  `json:",omitempty"`
+	Steps        []StepInfo `json:",omitempty"`
+	Cost         float64
+}
+
+// StepInfo is the time and cost of a multi-stage step within a run's
+// cluster-alive window.
+type StepInfo struct {
+	Name     string
+	Duration float64
+	Cost     float64
+}
+
+// ApplyRate sets the cost of the run and of each of its steps from their
+// billable hours and an hourly rate.
+func (j *JobInfo) ApplyRate(hourly float64) {
+	j.Cost = j.Duration * hourly
+	for i := range j.Steps {
+		j.Steps[i].Cost = j.Steps[i].Duration * hourly
+	}
+}
+
+type PRInfo struct {
+	Org           string
+	Repo          string
+	PRNum         int
+	PRLifeSpan    float64
+	PRRetestCount int
+	// PRRetestCount split by whether automation, such as
+	// openshift-ci-robot, or a person asked for the retest
+	BotRetests   int
+	HumanRetests int
+	// number of comments read from each source (issue, review, review_comment)
+	CommentCounts map[string]int `json:",omitempty"`
+	// number of times each Prow command (retest, test, override, hold, ...) was used
+	CommandCounts map[string]int `json:",omitempty"`
+	// retest commands per author and /test triggers per job name
+	RetestsByAuthor map[string]int `json:",omitempty"`
+	RetestsByJob    map[string]int `json:",omitempty"`
+	Jobs            []JobInfo
+	// cloud usage and cost of the jobs per platform
+	PlatformHours map[Platform]float64
+	PlatformCosts map[Platform]float64
+	TotalCost     float64
+	Coverage      Coverage
+	// TotalCost split by what the spend achieved
+	Spend Spend
+	// the PR's page, on the GitHub host the PR was read from
+	URL string `json:",omitempty"`
+	// problems hit while building the PR: errors leave its cost incomplete,
+	// warnings mark runs that were estimated or left out
+	Errors   []string `json:",omitempty"`
+	Warnings []string `json:",omitempty"`
+}
+
+// Key returns the org/repo#number of the PR.
+func (p PRInfo) Key() string {
+	return fmt.Sprintf("%s/%s#%d", p.Org, p.Repo, p.PRNum)
+}
+
+// Failed reports whether errors left the PR's cost incomplete.
+func (p PRInfo) Failed() bool {
+	return len(p.Errors) > 0
+}
+
+// ComputeTotals sets the per-platform hours and costs, TotalCost and Coverage
+// from the jobs of the PR.
+func (p *PRInfo) ComputeTotals() {
+	p.PlatformHours = make(map[Platform]float64)
+	p.PlatformCosts = make(map[Platform]float64)
+	p.TotalCost = 0
+	p.Coverage = Coverage{}
+	for _, job := range p.Jobs {
+		p.Coverage.Runs++
+		switch job.Status {
+		case StatusComplete:
+			p.Coverage.Complete++
+		case StatusRunning:
+			p.Coverage.Running++
+		case StatusAbortedNoFinish:
+			p.Coverage.Aborted++
+		case StatusFetchError:
+			p.Coverage.FetchErrors++
+		case StatusNoCluster:
+			p.Coverage.NoCluster++
+		}
+		if job.Status.Unfinished() && job.PricingMethod != MethodExcluded {
+			p.Coverage.Estimated++
+		}
+		if job.Platform == "" {
+			continue
+		}
+		p.PlatformHours[job.Platform] += job.Duration
+		p.PlatformCosts[job.Platform] += job.Cost
+		p.TotalCost += job.Cost
+	}
+	p.Spend = splitSpend(p.Jobs)
+}
+
+// Platforms returns the platforms the PR used, sorted by name.
+func (p PRInfo) Platforms() []Platform {
+	platforms := make([]Platform, 0, len(p.PlatformHours))
+	for platform := range p.PlatformHours {
+		platforms = append(platforms, platform)
+	}
+	sort.Slice(platforms, func(i, j int) bool { return platforms[i] < platforms[j] })
+	return platforms
+}
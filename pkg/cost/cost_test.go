@@ -0,0 +1,160 @@
+package cost
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestComputeTotals(t *testing.T) {
+	prInfo := PRInfo{
+		Org:   "openshift",
+		Repo:  "ovn-kubernetes",
+		PRNum: 1,
+		Jobs: []JobInfo{
+			{JobURL: "a", Platform: AWS, Status: StatusComplete, Duration: 2, Cost: 1.8},
+			{JobURL: "b", Platform: AWS, Status: StatusComplete, Duration: 1, Cost: 0.9},
+			{JobURL: "c", Platform: GCP, Status: StatusAbortedNoFinish, Duration: 1, Cost: 1.7, PricingMethod: MethodAbortTimeEstimate},
+			{JobURL: "d", Platform: Azure, Status: StatusRunning, PricingMethod: MethodExcluded},
+			{JobURL: "e", Platform: Vsphere, Status: StatusFetchError, PricingMethod: MethodExcluded},
+			{JobURL: "f", Status: StatusNoCluster},
+		},
+	}
+	prInfo.ComputeTotals()
+
+	if !approxEqual(prInfo.TotalCost, 4.4) {
+		t.Errorf("TotalCost = %v, want 4.4", prInfo.TotalCost)
+	}
+	wantHours := map[Platform]float64{AWS: 3, GCP: 1, Azure: 0, Vsphere: 0}
+	if len(prInfo.PlatformHours) != len(wantHours) {
+		t.Errorf("PlatformHours = %v, want %v", prInfo.PlatformHours, wantHours)
+	}
+	for platform, hours := range wantHours {
+		if !approxEqual(prInfo.PlatformHours[platform], hours) {
+			t.Errorf("PlatformHours[%s] = %v, want %v", platform, prInfo.PlatformHours[platform], hours)
+		}
+	}
+	if !approxEqual(prInfo.PlatformCosts[AWS], 2.7) {
+		t.Errorf("PlatformCosts[aws] = %v, want 2.7", prInfo.PlatformCosts[AWS])
+	}
+	wantCoverage := Coverage{Runs: 6, Complete: 2, Running: 1, Aborted: 1, FetchErrors: 1, NoCluster: 1, Estimated: 1}
+	if prInfo.Coverage != wantCoverage {
+		t.Errorf("Coverage = %+v, want %+v", prInfo.Coverage, wantCoverage)
+	}
+	if platforms := prInfo.Platforms(); len(platforms) != 4 || platforms[0] != AWS || platforms[3] != Vsphere {
+		t.Errorf("Platforms() = %v, want sorted aws..vsphere", platforms)
+	}
+}
+
+func TestComputeTotalsResets(t *testing.T) {
+	prInfo := PRInfo{Jobs: []JobInfo{{JobURL: "a", Platform: AWS, Status: StatusComplete, Duration: 1, Cost: 1}}}
+	prInfo.ComputeTotals()
+	prInfo.Jobs[0].Cost = 2
+	prInfo.ComputeTotals()
+	if prInfo.TotalCost != 2 || prInfo.Coverage.Runs != 1 {
+		t.Errorf("second ComputeTotals gave TotalCost %v and %d runs, want 2 and 1", prInfo.TotalCost, prInfo.Coverage.Runs)
+	}
+}
+
+func TestSplitSpend(t *testing.T) {
+	at := func(minutes int) time.Time {
+		return time.Date(2023, 7, 1, 0, minutes, 0, 0, time.UTC)
+	}
+	jobs := []JobInfo{
+		// aborted by a push of a newer commit
+		{JobName: "e2e-aws", SHA: "a", StartTime: at(0), Result: "ABORTED", Cost: 1},
+		// failed and retested on the same commit
+		{JobName: "e2e-aws", SHA: "b", StartTime: at(10), Result: "FAILURE", Cost: 2},
+		{JobName: "e2e-aws", SHA: "b", StartTime: at(20), Result: "SUCCESS", Cost: 4},
+		{JobName: "e2e-gcp", SHA: "b", StartTime: at(10), Result: "FAILURE", Cost: 8},
+		{JobName: "e2e-gcp-ovn", SHA: "b", StartTime: at(10), Status: StatusRunning, Cost: 16},
+		// free runs are left out
+		{JobName: "unit", SHA: "b", StartTime: at(10), Result: "SUCCESS"},
+	}
+	want := Spend{Superseded: 1, Retested: 2, Successful: 4, Failed: 8, Other: 16}
+	got := splitSpend(jobs)
+	if got != want {
+		t.Errorf("splitSpend() = %+v, want %+v", got, want)
+	}
+	if got.Wasted() != 11 || got.Total() != 31 {
+		t.Errorf("Wasted() = %v and Total() = %v, want 11 and 31", got.Wasted(), got.Total())
+	}
+}
+
+func TestBillableHours(t *testing.T) {
+	tests := []struct {
+		runHours, overhead, want float64
+	}{
+		{2, 0.5, 1.5},
+		{0.25, 0.5, 0},
+		{1.26, 0.5, 0.8},
+		{1, 0, 1},
+	}
+	for _, tt := range tests {
+		if got := BillableHours(tt.runHours, tt.overhead); !approxEqual(got, tt.want) {
+			t.Errorf("BillableHours(%v, %v) = %v, want %v", tt.runHours, tt.overhead, got, tt.want)
+		}
+	}
+}
+
+func TestOverhead(t *testing.T) {
+	defaultHours := 0.4
+	model := OverheadModel{
+		DefaultHours: &defaultHours,
+		Platforms: []PlatformOverhead{
+			{Platform: Vsphere, Hours: 0.75, Variants: []VariantOverhead{{Match: "hypershift", Hours: 0.1}}},
+		},
+	}
+	tests := []struct {
+		platform   Platform
+		jobName    string
+		wantHours  float64
+		wantMethod string
+	}{
+		{Vsphere, "e2e-vsphere-ovn", 0.75, MethodPlatformOverhead},
+		{Vsphere, "e2e-vsphere-hypershift", 0.1, MethodVariantOverhead},
+		{AWS, "e2e-aws-hypershift", 0.4, MethodDefaultOverhead},
+	}
+	for _, tt := range tests {
+		hours, method := model.Overhead(tt.platform, tt.jobName)
+		if hours != tt.wantHours || method != tt.wantMethod {
+			t.Errorf("Overhead(%s, %s) = %v, %s, want %v, %s", tt.platform, tt.jobName, hours, method, tt.wantHours, tt.wantMethod)
+		}
+	}
+	if hours, _ := (OverheadModel{}).Overhead(AWS, "e2e-aws"); hours != defaultOverheadHours {
+		t.Errorf("zero OverheadModel gave %v hours, want %v", hours, defaultOverheadHours)
+	}
+}
+
+func TestPlatformFromProfile(t *testing.T) {
+	tests := map[string]Platform{
+		"aws-2":                        AWS,
+		"gcp-openshift-gce-devel-ci-2": GCP,
+		"equinix-ocp-metal":            Metal,
+		"Azure4":                       Azure,
+		"libvirt-ppc64le":              Platform("libvirt-ppc64le"),
+		"":                             "",
+	}
+	for profile, want := range tests {
+		if got := PlatformFromProfile(profile); got != want {
+			t.Errorf("PlatformFromProfile(%q) = %q, want %q", profile, got, want)
+		}
+	}
+}
+
+func TestPlatformFromJobName(t *testing.T) {
+	tests := map[string]Platform{
+		"pull-ci-openshift-ovn-kubernetes-master-e2e-gcp-ovn-upgrade-from-aws": GCP,
+		"pull-ci-openshift-ovn-kubernetes-master-e2e-metal-ipi-ovn":            Metal,
+		"pull-ci-openshift-ovn-kubernetes-master-unit":                         "",
+	}
+	for jobName, want := range tests {
+		if got := PlatformFromJobName(jobName); got != want {
+			t.Errorf("PlatformFromJobName(%q) = %q, want %q", jobName, got, want)
+		}
+	}
+}
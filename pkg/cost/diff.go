@@ -0,0 +1,170 @@
+package cost
+
+import (
+	"math"
+	"sort"
+)
+
+// Tolerance is how much a duration or cost may move before a diff reports
+// it. A delta is reported when it is larger than both the absolute and the
+// relative tolerance.
+type Tolerance struct {
+	Hours float64
+	Cost  float64
+	// relative to the old value, in percent
+	Percent float64
+}
+
+func (t Tolerance) exceeded(old, new, absolute float64) bool {
+	delta := math.Abs(new - old)
+	if delta <= absolute {
+		return false
+	}
+	if t.Percent > 0 && old != 0 && delta/math.Abs(old)*100 <= t.Percent {
+		return false
+	}
+	return true
+}
+
+// Diff is how a report differs from an older one. PRs are matched by
+// org/repo#number and their jobs by URL.
+type Diff struct {
+	Old          string
+	New          string
+	Tolerance    Tolerance
+	OldTotalCost float64
+	NewTotalCost float64
+	// PRs only in one of the reports
+	AddedPRs   []string `json:",omitempty"`
+	RemovedPRs []string `json:",omitempty"`
+	// PRs in both reports that differ
+	Changed []PRDiff `json:",omitempty"`
+}
+
+// PRDiff is how a PR differs between two reports.
+type PRDiff struct {
+	PR string
+	// set when the cost moved by more than the tolerance, both 0 otherwise
+	OldCost float64
+	NewCost float64
+	// set when the retest count changed, both 0 otherwise
+	OldRetests int
+	NewRetests int
+	// URLs of runs only in one of the reports
+	AddedJobs   []string  `json:",omitempty"`
+	RemovedJobs []string  `json:",omitempty"`
+	Jobs        []JobDiff `json:",omitempty"`
+}
+
+// JobDiff is a run whose billable hours or cost moved by more than the
+// tolerance.
+type JobDiff struct {
+	JobURL      string
+	OldDuration float64
+	NewDuration float64
+	OldCost     float64
+	NewCost     float64
+}
+
+// Empty reports whether the reports match within the tolerance.
+func (d *Diff) Empty() bool {
+	return len(d.AddedPRs) == 0 && len(d.RemovedPRs) == 0 && len(d.Changed) == 0
+}
+
+// CostChanged reports whether the PR's cost moved by more than the tolerance.
+func (d PRDiff) CostChanged() bool {
+	return d.OldCost != d.NewCost
+}
+
+// RetestsChanged reports whether the PR's retest count changed.
+func (d PRDiff) RetestsChanged() bool {
+	return d.OldRetests != d.NewRetests
+}
+
+func (d PRDiff) empty() bool {
+	return !d.CostChanged() && !d.RetestsChanged() &&
+		len(d.AddedJobs) == 0 && len(d.RemovedJobs) == 0 && len(d.Jobs) == 0
+}
+
+// DiffReports compares the new report with the old one.
+func DiffReports(old, new *Report, tolerance Tolerance) *Diff {
+	d := &Diff{Tolerance: tolerance}
+	oldPRs := make(map[string]PRInfo, len(old.PRs))
+	for _, prInfo := range old.PRs {
+		oldPRs[prInfo.Key()] = prInfo
+		d.OldTotalCost += prInfo.TotalCost
+	}
+	newPRs := make(map[string]bool, len(new.PRs))
+	for _, prInfo := range new.PRs {
+		key := prInfo.Key()
+		newPRs[key] = true
+		d.NewTotalCost += prInfo.TotalCost
+		oldPR, ok := oldPRs[key]
+		if !ok {
+			d.AddedPRs = append(d.AddedPRs, key)
+			continue
+		}
+		if prDiff := diffPR(oldPR, prInfo, tolerance); !prDiff.empty() {
+			d.Changed = append(d.Changed, prDiff)
+		}
+	}
+	for key := range oldPRs {
+		if !newPRs[key] {
+			d.RemovedPRs = append(d.RemovedPRs, key)
+		}
+	}
+	sort.Strings(d.AddedPRs)
+	sort.Strings(d.RemovedPRs)
+	sort.Slice(d.Changed, func(i, j int) bool { return d.Changed[i].PR < d.Changed[j].PR })
+	return d
+}
+
+func diffPR(old, new PRInfo, tolerance Tolerance) PRDiff {
+	d := PRDiff{PR: new.Key()}
+	if tolerance.exceeded(old.TotalCost, new.TotalCost, tolerance.Cost) {
+		d.OldCost, d.NewCost = old.TotalCost, new.TotalCost
+	}
+	if old.PRRetestCount != new.PRRetestCount {
+		d.OldRetests, d.NewRetests = old.PRRetestCount, new.PRRetestCount
+	}
+
+	// older reports list runs that never had a cluster without a URL, so
+	// they cannot be matched
+	oldJobs := make(map[string]JobInfo, len(old.Jobs))
+	for _, job := range old.Jobs {
+		if job.JobURL != "" {
+			oldJobs[job.JobURL] = job
+		}
+	}
+	newJobs := make(map[string]bool, len(new.Jobs))
+	for _, job := range new.Jobs {
+		if job.JobURL == "" || newJobs[job.JobURL] {
+			continue
+		}
+		newJobs[job.JobURL] = true
+		oldJob, ok := oldJobs[job.JobURL]
+		if !ok {
+			d.AddedJobs = append(d.AddedJobs, job.JobURL)
+			continue
+		}
+		if tolerance.exceeded(oldJob.Duration, job.Duration, tolerance.Hours) ||
+			tolerance.exceeded(oldJob.Cost, job.Cost, tolerance.Cost) {
+			d.Jobs = append(d.Jobs, JobDiff{
+				JobURL:      job.JobURL,
+				OldDuration: oldJob.Duration,
+				NewDuration: job.Duration,
+				OldCost:     oldJob.Cost,
+				NewCost:     job.Cost,
+			})
+		}
+	}
+	for url := range oldJobs {
+		if !newJobs[url] {
+			d.RemovedJobs = append(d.RemovedJobs, url)
+		}
+	}
+	sort.Strings(d.AddedJobs)
+	sort.Strings(d.RemovedJobs)
+	sort.Slice(d.Jobs, func(i, j int) bool { return d.Jobs[i].JobURL < d.Jobs[j].JobURL })
+	return d
+}
@@ -0,0 +1,17 @@
+package cost
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestPRDiffJSONKeepsZeroCosts(t *testing.T) {
+	data, err := json.Marshal(PRDiff{PR: "openshift/ovn-kubernetes#1", OldCost: 5, NewCost: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"NewCost":0`) {
+		t.Errorf("PRDiff marshalled to %s, want the zero NewCost kept", data)
+	}
+}
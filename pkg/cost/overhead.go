@@ -0,0 +1,68 @@
+package cost
+
+import (
+	"math"
+	"strings"
+)
+
+// How a job's billable hours were derived from its run time.
+const (
+	MethodDefaultOverhead  = "default-overhead"
+	MethodPlatformOverhead = "platform-overhead"
+	MethodVariantOverhead  = "variant-overhead"
+	MethodMeasured         = "measured"
+)
+
+// defaultOverheadHours is the time a job spends before its cloud nodes are
+// provisioned when the rate card does not say otherwise.
+const defaultOverheadHours = 0.5
+
+// OverheadModel is the time a job runs before it provisions cloud
+// resources, which is not billed.
+type OverheadModel struct {
+	// overhead of platforms without an entry, 0.5h when unset
+	DefaultHours *float64           `yaml:"default_hours,omitempty" json:",omitempty"`
+	Platforms    []PlatformOverhead `yaml:"platforms,omitempty" json:",omitempty"`
+}
+
+type PlatformOverhead struct {
+	Platform Platform          `yaml:"platform"`
+	Hours    float64           `yaml:"hours"`
+	Variants []VariantOverhead `yaml:"variants,omitempty" json:",omitempty"`
+}
+
+// VariantOverhead overrides the platform overhead of the jobs whose name
+// contains Match, such as hypershift jobs that skip the cluster install.
+type VariantOverhead struct {
+	Match string  `yaml:"match"`
+	Hours float64 `yaml:"hours"`
+}
+
+// Overhead returns the unbilled hours of a job and the method they came from.
+func (m OverheadModel) Overhead(platform Platform, jobName string) (float64, string) {
+	for _, p := range m.Platforms {
+		if p.Platform != platform {
+			continue
+		}
+		for _, v := range p.Variants {
+			if strings.Contains(jobName, v.Match) {
+				return v.Hours, MethodVariantOverhead
+			}
+		}
+		return p.Hours, MethodPlatformOverhead
+	}
+	if m.DefaultHours != nil {
+		return *m.DefaultHours, MethodDefaultOverhead
+	}
+	return defaultOverheadHours, MethodDefaultOverhead
+}
+
+// BillableHours removes the overhead from a run time, without letting it go
+// negative, and rounds the result to one decimal.
+func BillableHours(runHours, overheadHours float64) float64 {
+	hours := runHours - overheadHours
+	if hours < 0.0 {
+		hours = 0.0
+	}
+	return math.Round(hours*10) / 10
+}
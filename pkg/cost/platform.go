@@ -0,0 +1,67 @@
+package cost
+
+import "strings"
+
+// Platform is the infrastructure a job's cluster runs on.
+type Platform string
+
+const (
+	AWS       Platform = "aws"
+	GCP       Platform = "gcp"
+	Azure     Platform = "azure"
+	Vsphere   Platform = "vsphere"
+	Metal     Platform = "metal"
+	OpenStack Platform = "openstack"
+	IBMCloud  Platform = "ibmcloud"
+	Nutanix   Platform = "nutanix"
+	PowerVS   Platform = "powervs"
+	Alibaba   Platform = "alibaba"
+)
+
+// cluster profile (and cloud label) prefixes, checked in order
+var profilePrefixes = []struct {
+	prefix   string
+	platform Platform
+}{
+	{"aws", AWS},
+	{"gcp", GCP},
+	{"azure", Azure},
+	{"vsphere", Vsphere},
+	{"equinix", Metal},
+	{"packet", Metal},
+	{"metal", Metal},
+	{"openstack", OpenStack},
+	{"ibmcloud", IBMCloud},
+	{"nutanix", Nutanix},
+	{"powervs", PowerVS},
+	{"alibaba", Alibaba},
+}
+
+// PlatformFromProfile returns the platform of a ci-operator cluster profile
+// such as aws-2, gcp-openshift-gce-devel-ci-2 or equinix-ocp-metal. Profiles
+// of unknown platforms are returned as they are so they can still be
+// reported, and an empty profile means the job has no cluster.
+func PlatformFromProfile(profile string) Platform {
+	profile = strings.ToLower(profile)
+	for _, p := range profilePrefixes {
+		if strings.HasPrefix(profile, p.prefix) {
+			return p.platform
+		}
+	}
+	return Platform(profile)
+}
+
+// PlatformFromJobName guesses the platform from the first platform named in
+// the dash separated words of a job name, so that
+// e2e-gcp-ovn-upgrade-from-aws is a GCP job. It is only a fallback for runs
+// whose prowjob.json cannot be read.
+func PlatformFromJobName(jobName string) Platform {
+	for _, word := range strings.Split(strings.ToLower(jobName), "-") {
+		for _, p := range profilePrefixes {
+			if word == p.prefix || word == string(p.platform) {
+				return p.platform
+			}
+		}
+	}
+	return ""
+}
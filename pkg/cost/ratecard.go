@@ -0,0 +1,155 @@
+package cost
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+const dateFormat = "2006-01-02"
+
+//go:embed ratecard.yaml
+var defaultRateCard []byte
+
+// RateCard holds the hourly cost of a cluster per platform over time.
+type RateCard struct {
+	Version  string `yaml:"version"`
+	Currency string `yaml:"currency"`
+	Rates    []Rate `yaml:"rates"`
+	// unbilled time before a job's cloud resources are provisioned
+	Overhead OverheadModel `yaml:"overhead,omitempty"`
+}
+
+// Rate is the hourly cost of a platform between EffectiveFrom and the end of
+// the EffectiveTo day, which is open ended when empty.
+type Rate struct {
+	Platform      Platform  `yaml:"platform"`
+	Hourly        float64   `yaml:"hourly"`
+	EffectiveFrom string    `yaml:"effective_from"`
+	EffectiveTo   string    `yaml:"effective_to,omitempty" json:",omitempty"`
+	Variants      []Variant `yaml:"variants,omitempty" json:",omitempty"`
+
+	from, to time.Time
+}
+
+// Variant scales the rate of the jobs whose name contains Match, such as
+// upgrade jobs that run two clusters' worth of installs.
+type Variant struct {
+	Match      string  `yaml:"match"`
+	Multiplier float64 `yaml:"multiplier"`
+}
+
+// DefaultRateCard returns the rate card built into the binary.
+func DefaultRateCard() *RateCard {
+	rc, err := ParseRateCard(defaultRateCard)
+	if err != nil {
+		panic(fmt.Sprintf("invalid built-in rate card: %v", err))
+	}
+	return rc
+}
+
+// LoadRateCard reads a rate card from a YAML file.
+func LoadRateCard(path string) (*RateCard, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := ParseRateCard(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rate card %s: %v", path, err)
+	}
+	return rc, nil
+}
+
+// ParseRateCard parses and validates a YAML rate card.
+func ParseRateCard(data []byte) (*RateCard, error) {
+	var rc RateCard
+	if err := yaml.UnmarshalStrict(data, &rc); err != nil {
+		return nil, err
+	}
+	if err := rc.init(); err != nil {
+		return nil, err
+	}
+	return &rc, nil
+}
+
+// init parses the effective dates and checks that no two rates of a platform
+// apply at the same time.
+func (rc *RateCard) init() error {
+	if rc.Version == "" {
+		return fmt.Errorf("missing version")
+	}
+	if rc.Overhead.DefaultHours != nil && *rc.Overhead.DefaultHours < 0 {
+		return fmt.Errorf("overhead default_hours is negative")
+	}
+	for _, p := range rc.Overhead.Platforms {
+		if p.Hours < 0 {
+			return fmt.Errorf("overhead of %s is negative", p.Platform)
+		}
+		for _, v := range p.Variants {
+			if v.Hours < 0 {
+				return fmt.Errorf("overhead of %s %s jobs is negative", p.Platform, v.Match)
+			}
+		}
+	}
+	for i := range rc.Rates {
+		r := &rc.Rates[i]
+		if r.Platform == "" {
+			return fmt.Errorf("rate %d has no platform", i)
+		}
+		var err error
+		r.from, err = time.Parse(dateFormat, r.EffectiveFrom)
+		if err != nil {
+			return fmt.Errorf("rate %d (%s): invalid effective_from: %v", i, r.Platform, err)
+		}
+		if r.EffectiveTo != "" {
+			r.to, err = time.Parse(dateFormat, r.EffectiveTo)
+			if err != nil {
+				return fmt.Errorf("rate %d (%s): invalid effective_to: %v", i, r.Platform, err)
+			}
+			// the rate applies for the whole effective_to day
+			r.to = r.to.AddDate(0, 0, 1)
+			if !r.to.After(r.from) {
+				return fmt.Errorf("rate %d (%s): effective_to is before effective_from", i, r.Platform)
+			}
+		}
+		for j := 0; j < i; j++ {
+			if rc.Rates[j].Platform == r.Platform && rc.Rates[j].overlaps(*r) {
+				return fmt.Errorf("rates %d and %d of %s overlap", j, i, r.Platform)
+			}
+		}
+	}
+	return nil
+}
+
+func (r Rate) overlaps(o Rate) bool {
+	return (r.to.IsZero() || o.from.Before(r.to)) && (o.to.IsZero() || r.from.Before(o.to))
+}
+
+func (r Rate) appliesAt(at time.Time) bool {
+	return !at.Before(r.from) && (r.to.IsZero() || at.Before(r.to))
+}
+
+// Price returns the hourly rate of a job on platform that started at the
+// given time, with the multiplier of the first variant matching the job
+// name applied. ok is false when no rate covers that time.
+func (rc *RateCard) Price(platform Platform, jobName string, at time.Time) (hourly float64, ok bool) {
+	for _, r := range rc.Rates {
+		if r.Platform != platform || !r.appliesAt(at) {
+			continue
+		}
+		hourly = r.Hourly
+		for _, v := range r.Variants {
+			if strings.Contains(jobName, v.Match) {
+				hourly *= v.Multiplier
+				break
+			}
+		}
+		return hourly, true
+	}
+	return 0, false
+}
@@ -0,0 +1,114 @@
+package cost
+
+import (
+	"testing"
+	"time"
+)
+
+const testRateCard = `
+version: "test"
+currency: USD
+rates:
+  - platform: aws
+    hourly: 1.0
+    effective_from: "2023-01-01"
+    effective_to: "2023-06-30"
+    variants:
+      - match: upgrade
+        multiplier: 2
+  - platform: aws
+    hourly: 1.5
+    effective_from: "2023-07-01"
+  - platform: gcp
+    hourly: 2.0
+    effective_from: "2023-01-01"
+`
+
+func TestPrice(t *testing.T) {
+	rc, err := ParseRateCard([]byte(testRateCard))
+	if err != nil {
+		t.Fatalf("ParseRateCard() failed: %v", err)
+	}
+	day := func(s string) time.Time {
+		d, err := time.Parse("2006-01-02T15:04", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	tests := []struct {
+		platform Platform
+		jobName  string
+		at       time.Time
+		want     float64
+		wantOK   bool
+	}{
+		{AWS, "e2e-aws", day("2023-03-01T00:00"), 1.0, true},
+		{AWS, "e2e-aws-upgrade", day("2023-03-01T00:00"), 2.0, true},
+		// effective_to covers the whole day
+		{AWS, "e2e-aws", day("2023-06-30T23:59"), 1.0, true},
+		{AWS, "e2e-aws-upgrade", day("2023-07-01T00:00"), 1.5, true},
+		{GCP, "e2e-gcp", day("2024-01-01T00:00"), 2.0, true},
+		{AWS, "e2e-aws", day("2022-12-31T23:59"), 0, false},
+		{Azure, "e2e-azure", day("2023-03-01T00:00"), 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := rc.Price(tt.platform, tt.jobName, tt.at)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("Price(%s, %s, %s) = %v, %t, want %v, %t", tt.platform, tt.jobName, tt.at, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestParseRateCardInvalid(t *testing.T) {
+	tests := map[string]string{
+		"no version": `
+rates:
+  - platform: aws
+    hourly: 1
+    effective_from: "2023-01-01"`,
+		"overlapping rates": `
+version: "1"
+rates:
+  - platform: aws
+    hourly: 1
+    effective_from: "2023-01-01"
+  - platform: aws
+    hourly: 2
+    effective_from: "2023-06-01"`,
+		"bad date": `
+version: "1"
+rates:
+  - platform: aws
+    hourly: 1
+    effective_from: "01-01-2023"`,
+		"end before start": `
+version: "1"
+rates:
+  - platform: aws
+    hourly: 1
+    effective_from: "2023-06-01"
+    effective_to: "2023-01-01"`,
+		"negative overhead": `
+version: "1"
+overhead:
+  default_hours: -1`,
+		"unknown field": `
+version: "1"
+rate: []`,
+	}
+	for name, data := range tests {
+		if _, err := ParseRateCard([]byte(data)); err == nil {
+			t.Errorf("%s: ParseRateCard() succeeded, want an error", name)
+		}
+	}
+}
+
+func TestDefaultRateCard(t *testing.T) {
+	rc := DefaultRateCard()
+	for _, platform := range []Platform{AWS, GCP, Azure, Vsphere} {
+		if _, ok := rc.Price(platform, "e2e", time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)); !ok {
+			t.Errorf("built-in rate card has no %s rate", platform)
+		}
+	}
+}
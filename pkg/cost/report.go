@@ -0,0 +1,59 @@
+package cost
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Report is the result of a PR cost run: the PRs and the rate card they were
+// priced with.
+type Report struct {
+	GeneratedAt time.Time
+	RateCard    *RateCard
+	PRs         []PRInfo
+	// set when the run was interrupted before every PR was processed;
+	// pr-costs -resume completes the report
+	Partial bool `json:",omitempty"`
+	// why a partial run stopped and the URLs of the PRs it did not process
+	Interrupted string   `json:",omitempty"`
+	Unprocessed []string `json:",omitempty"`
+}
+
+// PRKeys returns the org/repo#number of every PR in the report.
+func (r *Report) PRKeys() map[string]bool {
+	keys := make(map[string]bool, len(r.PRs))
+	for _, prInfo := range r.PRs {
+		keys[prInfo.Key()] = true
+	}
+	return keys
+}
+
+// ReadReport reads a report written by the pr-costs command. Reports written
+// before the rate card was recorded, which are a plain list of PRs, are read
+// with a nil RateCard. A recorded rate card is validated so it can price jobs
+// again.
+func ReadReport(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var report Report
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(data, &report.PRs)
+	} else {
+		err = json.Unmarshal(data, &report)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse report %s: %v", path, err)
+	}
+	if report.RateCard != nil {
+		if err := report.RateCard.init(); err != nil {
+			return nil, fmt.Errorf("invalid rate card in report %s: %v", path, err)
+		}
+	}
+	return &report, nil
+}
@@ -0,0 +1,117 @@
+package cost
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testReport(t *testing.T) *Report {
+	t.Helper()
+	rc, err := ParseRateCard([]byte(testRateCard))
+	if err != nil {
+		t.Fatalf("ParseRateCard() failed: %v", err)
+	}
+	started := time.Date(2023, 7, 3, 10, 0, 0, 0, time.UTC)
+	prInfo := PRInfo{
+		Org:             "openshift",
+		Repo:            "cluster-network-operator",
+		PRNum:           1800,
+		PRLifeSpan:      2.5,
+		PRRetestCount:   2,
+		CommentCounts:   map[string]int{"issue": 4, "review": 1},
+		CommandCounts:   map[string]int{"retest": 1, "test": 1},
+		RetestsByAuthor: map[string]int{"someone": 2},
+		RetestsByJob:    map[string]int{"pull-ci-openshift-cluster-network-operator-master-e2e-aws-ovn": 1},
+		Jobs: []JobInfo{
+			{
+				JobURL:        "https://prow.ci.openshift.org/view/gs/test-platform-results/pr-logs/pull/openshift_cluster-network-operator/1800/pull-ci-openshift-cluster-network-operator-master-e2e-aws-ovn/1676000000000000000",
+				JobName:       "pull-ci-openshift-cluster-network-operator-master-e2e-aws-ovn",
+				Platform:      AWS,
+				Status:        StatusComplete,
+				Result:        "SUCCESS",
+				SHA:           "abc123",
+				StartTime:     started,
+				Duration:      1.5,
+				OverheadHours: 0.5,
+				PricingMethod: MethodMeasured,
+				ClusterStart:  started.Add(30 * time.Minute),
+				ClusterEnd:    started.Add(2 * time.Hour),
+				Steps:         []StepInfo{{Name: "ipi-install-install", Duration: 0.75, Cost: 1.125}},
+				Cost:          2.25,
+			},
+			{
+				JobURL:    "https://prow.ci.openshift.org/view/gs/test-platform-results/pr-logs/pull/openshift_cluster-network-operator/1800/pull-ci-openshift-cluster-network-operator-master-unit/1676000000000000001",
+				JobName:   "pull-ci-openshift-cluster-network-operator-master-unit",
+				Status:    StatusNoCluster,
+				StartTime: started,
+			},
+		},
+		Warnings: []string{"platform guessed"},
+	}
+	prInfo.ComputeTotals()
+	return &Report{
+		GeneratedAt: time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC),
+		RateCard:    rc,
+		PRs:         []PRInfo{prInfo},
+		Partial:     true,
+		Interrupted: "context canceled",
+		Unprocessed: []string{"https://github.com/openshift/cluster-network-operator/pull/1801"},
+	}
+}
+
+func writeTestFile(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "report.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReportRoundTrip(t *testing.T) {
+	want := testReport(t)
+	got, err := ReadReport(writeTestFile(t, want))
+	if err != nil {
+		t.Fatalf("ReadReport() failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadReport() = %+v, want %+v", got, want)
+	}
+	// the recorded rate card still prices by date
+	if rate, ok := got.RateCard.Price(AWS, "e2e-aws", time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)); !ok || rate != 1.0 {
+		t.Errorf("recorded rate card priced aws at %v, %t, want 1.0, true", rate, ok)
+	}
+}
+
+func TestReadReportPRList(t *testing.T) {
+	// reports written before the rate card was recorded are a list of PRs
+	want := testReport(t).PRs
+	got, err := ReadReport(writeTestFile(t, want))
+	if err != nil {
+		t.Fatalf("ReadReport() failed: %v", err)
+	}
+	if got.RateCard != nil {
+		t.Errorf("ReadReport() of a PR list has a rate card")
+	}
+	if !reflect.DeepEqual(got.PRs, want) {
+		t.Errorf("ReadReport() PRs = %+v, want %+v", got.PRs, want)
+	}
+}
+
+func TestReadReportInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+	if err := os.WriteFile(path, []byte(`{"PRs": [`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadReport(path); err == nil {
+		t.Errorf("ReadReport() of truncated JSON succeeded")
+	}
+}
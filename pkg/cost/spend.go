@@ -0,0 +1,96 @@
+package cost
+
+import (
+	"sort"
+	"strings"
+)
+
+// Spend splits the cost of job runs by outcome. Every run falls in exactly
+// one category, checked in this order:
+//   - Superseded: aborted runs followed by a run of the same job on a newer
+//     commit, usually aborted by Prow when the PR was pushed to
+//   - Retested: runs followed by a run of the same job on the same commit,
+//     such as failures re-run by /retest
+//   - Successful: runs that passed
+//   - Failed: runs that failed or errored
+//   - Other: everything else, such as runs without a recorded result
+type Spend struct {
+	Successful float64
+	Failed     float64
+	Retested   float64
+	Superseded float64
+	Other      float64
+}
+
+// Wasted returns the spend that did not produce a final result for a commit.
+func (s Spend) Wasted() float64 {
+	return s.Failed + s.Retested + s.Superseded
+}
+
+// Total returns the spend of every category.
+func (s Spend) Total() float64 {
+	return s.Successful + s.Failed + s.Retested + s.Superseded + s.Other
+}
+
+// Add adds the spend of o to s.
+func (s *Spend) Add(o Spend) {
+	s.Successful += o.Successful
+	s.Failed += o.Failed
+	s.Retested += o.Retested
+	s.Superseded += o.Superseded
+	s.Other += o.Other
+}
+
+func splitSpend(jobs []JobInfo) Spend {
+	byJob := make(map[string][]JobInfo)
+	for _, job := range jobs {
+		if job.Cost == 0 {
+			continue
+		}
+		byJob[job.JobName] = append(byJob[job.JobName], job)
+	}
+
+	var spend Spend
+	for _, runs := range byJob {
+		sort.SliceStable(runs, func(i, j int) bool {
+			return runs[i].StartTime.Before(runs[j].StartTime)
+		})
+		for i, run := range runs {
+			newerCommit, sameCommit := false, false
+			for _, later := range runs[i+1:] {
+				if run.SHA != "" && later.SHA != "" && later.SHA != run.SHA {
+					newerCommit = true
+				}
+				if run.SHA != "" && later.SHA == run.SHA {
+					sameCommit = true
+				}
+			}
+			aborted := strings.EqualFold(run.Result, "ABORTED") || run.Status == StatusAbortedNoFinish
+			switch {
+			case aborted && newerCommit:
+				spend.Superseded += run.Cost
+			case sameCommit:
+				spend.Retested += run.Cost
+			case strings.EqualFold(run.Result, "SUCCESS"):
+				spend.Successful += run.Cost
+			case strings.EqualFold(run.Result, "FAILURE") || strings.EqualFold(run.Result, "ERROR"):
+				spend.Failed += run.Cost
+			default:
+				spend.Other += run.Cost
+			}
+		}
+	}
+	return spend
+}
+
+// SpendByRepo adds up the spend of the PRs per org/repo.
+func SpendByRepo(prInfos []PRInfo) map[string]Spend {
+	byRepo := make(map[string]Spend)
+	for _, prInfo := range prInfos {
+		key := prInfo.Org + "/" + prInfo.Repo
+		spend := byRepo[key]
+		spend.Add(prInfo.Spend)
+		byRepo[key] = spend
+	}
+	return byRepo
+}
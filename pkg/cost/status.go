@@ -0,0 +1,55 @@
+package cost
+
+// JobStatus tells whether a job run's timings could be read.
+type JobStatus string
+
+const (
+	StatusComplete = JobStatus("complete")
+	// the run has not finished yet
+	StatusRunning = JobStatus("running")
+	// the run ended without writing finished.json, usually because it was
+	// aborted
+	StatusAbortedNoFinish = JobStatus("aborted-no-finish")
+	// the run's artifacts could not be fetched or parsed
+	StatusFetchError = JobStatus("fetch-error")
+	// the job has no cloud cluster, so there is nothing to price
+	StatusNoCluster = JobStatus("no-cluster")
+)
+
+// How unfinished runs, those running or aborted without finished.json, are
+// priced.
+const (
+	// unfinished runs cost nothing
+	PolicyExclude = "exclude"
+	// unfinished runs are priced from their start until they were aborted,
+	// or until now when they are still running
+	PolicyAbortTime = "abort-time"
+	// unfinished runs are priced at the median of the complete runs of the
+	// same job
+	PolicyMedian = "median"
+)
+
+// Pricing methods of unfinished runs.
+const (
+	MethodAbortTimeEstimate = "abort-time-estimate"
+	MethodMedianEstimate    = "median-estimate"
+	MethodExcluded          = "excluded"
+)
+
+// Coverage counts the job runs of a PR by status, so a PR whose timings were
+// partly unreadable can be told from a cheap one.
+type Coverage struct {
+	Runs        int
+	Complete    int
+	Running     int
+	Aborted     int
+	FetchErrors int
+	NoCluster   int
+	// unfinished runs priced with an estimate rather than excluded
+	Estimated int
+}
+
+// Unfinished reports whether the run has no finish time to price it with.
+func (s JobStatus) Unfinished() bool {
+	return s == StatusRunning || s == StatusAbortedNoFinish
+}
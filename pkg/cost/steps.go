@@ -0,0 +1,43 @@
+package cost
+
+import "sort"
+
+// StepTotal is the spend on a step across many job runs.
+type StepTotal struct {
+	Name  string
+	Runs  int
+	Hours float64
+	Cost  float64
+}
+
+// StepTotals adds up the step costs of every job of the PRs and returns them
+// from most to least expensive.
+func StepTotals(prInfos []PRInfo) []StepTotal {
+	totals := make(map[string]*StepTotal)
+	for _, prInfo := range prInfos {
+		for _, job := range prInfo.Jobs {
+			for _, step := range job.Steps {
+				total, ok := totals[step.Name]
+				if !ok {
+					total = &StepTotal{Name: step.Name}
+					totals[step.Name] = total
+				}
+				total.Runs++
+				total.Hours += step.Duration
+				total.Cost += step.Cost
+			}
+		}
+	}
+
+	sorted := make([]StepTotal, 0, len(totals))
+	for _, total := range totals {
+		sorted = append(sorted, *total)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Cost != sorted[j].Cost {
+			return sorted[i].Cost > sorted[j].Cost
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
+}
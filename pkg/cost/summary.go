@@ -0,0 +1,82 @@
+package cost
+
+import (
+	"sort"
+	"time"
+)
+
+// Summary condenses the PRs of one repo, or of one repo over one period, into
+// the figures reports compare side by side.
+type Summary struct {
+	Org  string
+	Repo string
+	// the report file the summary was made from
+	Report         string `json:",omitempty"`
+	PRs            int
+	FailedPRs      int
+	TotalCost      float64
+	MeanPRCost     float64
+	MedianPRCost   float64
+	RetestsPerPR   float64
+	MedianLifespan float64
+	PlatformCosts  map[Platform]float64
+	Spend          Spend
+	Partial        bool `json:",omitempty"`
+}
+
+// Summarize builds the summary of the PRs of org/repo.
+func Summarize(org, repo string, prInfos []PRInfo) Summary {
+	s := Summary{
+		Org:           org,
+		Repo:          repo,
+		PRs:           len(prInfos),
+		PlatformCosts: make(map[Platform]float64),
+	}
+	var costs, lifespans []float64
+	retests := 0
+	for _, prInfo := range prInfos {
+		if prInfo.Failed() {
+			s.FailedPRs++
+		}
+		s.TotalCost += prInfo.TotalCost
+		for platform, c := range prInfo.PlatformCosts {
+			s.PlatformCosts[platform] += c
+		}
+		s.Spend.Add(prInfo.Spend)
+		retests += prInfo.PRRetestCount
+		costs = append(costs, prInfo.TotalCost)
+		lifespans = append(lifespans, prInfo.PRLifeSpan)
+	}
+	if len(prInfos) > 0 {
+		s.MeanPRCost = s.TotalCost / float64(len(prInfos))
+		s.RetestsPerPR = float64(retests) / float64(len(prInfos))
+		s.MedianPRCost = Median(costs)
+		s.MedianLifespan = Median(lifespans)
+	}
+	return s
+}
+
+// CombinedReport compares the repos of a multi-repo run side by side.
+type CombinedReport struct {
+	GeneratedAt time.Time
+	Period      string
+	Start       time.Time
+	End         time.Time
+	Repos       []Summary
+	// set when any repo's report is partial
+	Partial bool `json:",omitempty"`
+}
+
+// Median returns the median of values, or 0 when there are none.
+func Median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
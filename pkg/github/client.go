@@ -0,0 +1,218 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultBaseURL = "https://api.github.com"
+	// how many times a request is retried after hitting a rate limit
+	maxRateLimitRetries = 5
+	// GitHub asks to wait at least a minute after a secondary rate limit
+	// response that carries no Retry-After header
+	secondaryRateLimitWait = time.Minute
+)
+
+// Client talks to the GitHub API, authenticating with a token when one is set
+// and waiting out primary and secondary rate limits.
+type Client struct {
+	httpClient *http.Client
+	token      string
+	baseURL    string
+
+	mu sync.Mutex
+	// the time each exhausted primary rate limit resets at, keyed by the
+	// resource it limits: core, search or graphql
+	resetAt map[string]time.Time
+}
+
+// NewClient returns a Client. An empty token makes anonymous requests, which
+// GitHub limits to 60 per hour.
+func NewClient(token string) *Client {
+	return &Client{
+		httpClient: http.DefaultClient,
+		token:      token,
+		baseURL:    defaultBaseURL,
+		resetAt:    make(map[string]time.Time),
+	}
+}
+
+// LoadToken returns the contents of tokenFile when it is set, otherwise the
+// GITHUB_TOKEN or GH_TOKEN environment variable.
+func LoadToken(tokenFile string) (string, error) {
+	if tokenFile != "" {
+		data, err := os.ReadFile(tokenFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read token file: %v", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		return token, nil
+	}
+	return os.Getenv("GH_TOKEN"), nil
+}
+
+// SetBaseURL points the client at a different API root, such as a GitHub
+// Enterprise server or a local fake.
+func (c *Client) SetBaseURL(baseURL string) {
+	c.baseURL = strings.TrimSuffix(baseURL, "/")
+}
+
+// SetHTTPClient sends the client's requests through httpClient, such as one
+// backed by a response cache.
+func (c *Client) SetHTTPClient(httpClient *http.Client) {
+	c.httpClient = httpClient
+}
+
+// get performs an authenticated GET request, retrying when GitHub reports
+// that a rate limit was hit. The caller must close the response body.
+func (c *Client) get(ctx context.Context, url string) (*http.Response, error) {
+	return c.do(ctx, "GET", url, nil)
+}
+
+func (c *Client) do(ctx context.Context, method, url string, body []byte) (*http.Response, error) {
+	resource := rateLimitResource(url)
+	for attempt := 0; ; attempt++ {
+		if err := c.waitForReset(ctx, resource); err != nil {
+			return nil, err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/vnd.github.v3+json")
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		c.updateRateLimit(resp, resource)
+
+		wait, limited := rateLimitWait(resp, attempt)
+		if !limited {
+			return resp, nil
+		}
+		resp.Body.Close()
+		if attempt >= maxRateLimitRetries {
+			return nil, fmt.Errorf("rate limited by GitHub after %d retries: %s", attempt, resp.Status)
+		}
+		log.Printf("GitHub rate limit hit, waiting %s before retrying %s", wait.Round(time.Second), url)
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// rateLimitResource returns the rate limit resource GitHub counts a request
+// to url against. Search and GraphQL have limits of their own, so running out
+// of one does not hold up requests counted against the others.
+func rateLimitResource(url string) string {
+	switch {
+	case strings.Contains(url, "/search/"):
+		return "search"
+	case strings.HasSuffix(url, "/graphql"):
+		return "graphql"
+	}
+	return "core"
+}
+
+// waitForReset blocks until the primary rate limit of resource resets when it
+// is known to be exhausted.
+func (c *Client) waitForReset(ctx context.Context, resource string) error {
+	c.mu.Lock()
+	wait := time.Until(c.resetAt[resource])
+	c.mu.Unlock()
+	if wait <= 0 {
+		return nil
+	}
+	log.Printf("GitHub %s rate limit exhausted, waiting %s for it to reset", resource, wait.Round(time.Second))
+	return sleep(ctx, wait)
+}
+
+// sleep waits for d, returning early with the context's error when it is
+// cancelled first.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// updateRateLimit records when the rate limit of the resource a response was
+// counted against resets, if the response exhausted it. The resource is taken
+// from the X-RateLimit-Resource header, falling back to the one the request
+// was expected to use.
+func (c *Client) updateRateLimit(resp *http.Response, resource string) {
+	if resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		return
+	}
+	reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+	if r := resp.Header.Get("X-RateLimit-Resource"); r != "" {
+		resource = r
+	}
+	c.mu.Lock()
+	c.resetAt[resource] = time.Unix(reset, 0)
+	c.mu.Unlock()
+}
+
+// rateLimitWait reports whether resp is a rate limit response and how long to
+// wait before retrying it.
+func rateLimitWait(resp *http.Response, attempt int) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil {
+			return time.Duration(seconds) * time.Second, true
+		}
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+		if err == nil {
+			// the reset time has one second resolution, so add one to be safe
+			return time.Until(time.Unix(reset, 0)) + time.Second, true
+		}
+	}
+	if resp.StatusCode == http.StatusTooManyRequests || isSecondaryRateLimit(resp) {
+		return secondaryRateLimitWait << attempt, true
+	}
+	// any other 403 is a permission problem rather than a rate limit
+	return 0, false
+}
+
+// isSecondaryRateLimit checks the body of a 403 response for the secondary
+// rate limit message. The body is left readable for the caller.
+func isSecondaryRateLimit(resp *http.Response) bool {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+	return strings.Contains(strings.ToLower(string(body)), "secondary rate limit")
+}
@@ -0,0 +1,130 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimitResource(t *testing.T) {
+	tests := map[string]string{
+		"https://api.github.com/search/issues?q=repo:a/b":        "search",
+		"https://api.github.com/graphql":                         "graphql",
+		"https://github.example.com/api/graphql":                 "graphql",
+		"https://api.github.com/repos/a/b/issues/1/comments":     "core",
+		"https://github.example.com/api/v3/repos/a/b/pulls/1":    "core",
+		"https://github.example.com/api/v3/search/issues?q=repo": "search",
+	}
+	for url, want := range tests {
+		if got := rateLimitResource(url); got != want {
+			t.Errorf("rateLimitResource(%q) = %q, want %q", url, got, want)
+		}
+	}
+}
+
+// An exhausted search limit must not hold up core requests.
+func TestRateLimitPerResource(t *testing.T) {
+	reset := time.Now().Add(time.Hour).Unix()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/search/") {
+			w.Header().Set("X-RateLimit-Resource", "search")
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", fmt.Sprint(reset))
+		} else {
+			w.Header().Set("X-RateLimit-Resource", "core")
+			w.Header().Set("X-RateLimit-Remaining", "4999")
+		}
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	c := NewClient("token")
+	c.SetBaseURL(server.URL)
+	ctx := context.Background()
+
+	resp, err := c.get(ctx, server.URL+"/search/issues?q=x")
+	if err != nil {
+		t.Fatalf("search request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	shortCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	resp, err = c.get(shortCtx, server.URL+"/repos/a/b/issues/1/comments")
+	if err != nil {
+		t.Fatalf("core request waited for the search limit: %v", err)
+	}
+	resp.Body.Close()
+
+	shortCtx, cancel = context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.get(shortCtx, server.URL+"/search/issues?q=y"); err == nil {
+		t.Errorf("search request did not wait for the search limit to reset")
+	}
+}
+
+func TestRateLimitWait(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		header      map[string]string
+		body        string
+		wantLimited bool
+		wantWait    time.Duration
+	}{
+		{name: "ok", status: http.StatusOK},
+		{name: "retry after", status: http.StatusForbidden, header: map[string]string{"Retry-After": "30"}, wantLimited: true, wantWait: 30 * time.Second},
+		{name: "secondary", status: http.StatusForbidden, body: "You have exceeded a secondary rate limit", wantLimited: true, wantWait: secondaryRateLimitWait},
+		{name: "too many requests", status: http.StatusTooManyRequests, wantLimited: true, wantWait: secondaryRateLimitWait},
+		{name: "permission", status: http.StatusForbidden, body: "Resource not accessible"},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{StatusCode: tt.status, Header: make(http.Header), Body: http.NoBody}
+		for k, v := range tt.header {
+			resp.Header.Set(k, v)
+		}
+		if tt.body != "" {
+			resp.Body = io.NopCloser(strings.NewReader(tt.body))
+		}
+		wait, limited := rateLimitWait(resp, 0)
+		if limited != tt.wantLimited || wait != tt.wantWait {
+			t.Errorf("%s: rateLimitWait() = %s, %t, want %s, %t", tt.name, wait, limited, tt.wantWait, tt.wantLimited)
+		}
+	}
+}
+
+func TestLoadToken(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name        string
+		tokenFile   string
+		githubToken string
+		ghToken     string
+		want        string
+		wantErr     bool
+	}{
+		{name: "file first", tokenFile: tokenFile, githubToken: "github", ghToken: "gh", want: "from-file"},
+		{name: "GITHUB_TOKEN before GH_TOKEN", githubToken: "github", ghToken: "gh", want: "github"},
+		{name: "GH_TOKEN", ghToken: "gh", want: "gh"},
+		{name: "none", want: ""},
+		{name: "missing file", tokenFile: filepath.Join(t.TempDir(), "none"), githubToken: "github", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Setenv("GITHUB_TOKEN", tt.githubToken)
+		t.Setenv("GH_TOKEN", tt.ghToken)
+		got, err := LoadToken(tt.tokenFile)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("%s: LoadToken() = %q, %v, want %q, error %t", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
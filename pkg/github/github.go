@@ -0,0 +1,217 @@
+// Package github discovers closed pull requests and their comments using the
+// GitHub REST or GraphQL API.
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Source finds closed pull requests and their comments. Client uses the REST
+// API and GraphQLClient the GraphQL API.
+type Source interface {
+	GetClosedPullRequests(ctx context.Context, owner, repo string, startTime, endTime time.Time) ([]PullRequest, error)
+	GetPRComments(ctx context.Context, owner, repo string, prNumber int) ([]Comment, error)
+}
+
+type PullRequest struct {
+	Number    int        `json:"number"`
+	Title     string     `json:"title"`
+	URL       string     `json:"html_url"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	ClosedAt  time.Time  `json:"closed_at"`
+	MergedAt  *time.Time `json:"merged_at,omitempty"`
+	Labels    []Label    `json:"labels"`
+	// only filled in by the GraphQL source
+	Commits        []Commit        `json:"commits,omitempty"`
+	TimelineEvents []TimelineEvent `json:"timeline_events,omitempty"`
+}
+
+// Merged reports whether the PR was merged rather than closed unmerged.
+func (pr PullRequest) Merged() bool {
+	return pr.MergedAt != nil
+}
+
+type Label struct {
+	Name string `json:"name"`
+}
+
+type User struct {
+	Login string `json:"login"`
+}
+
+// CommentSource tells where on a PR a comment was written.
+type CommentSource string
+
+const (
+	IssueComment  CommentSource = "issue"
+	ReviewComment CommentSource = "review_comment"
+	ReviewBody    CommentSource = "review"
+)
+
+type Comment struct {
+	Body      string        `json:"body"`
+	User      User          `json:"user"`
+	CreatedAt time.Time     `json:"created_at"`
+	Source    CommentSource `json:"-"`
+}
+
+type review struct {
+	Body        string    `json:"body"`
+	User        User      `json:"user"`
+	SubmittedAt time.Time `json:"submitted_at"`
+}
+
+type Commit struct {
+	SHA           string    `json:"sha"`
+	CommittedDate time.Time `json:"committed_date"`
+}
+
+// TimelineEvent is a PR timeline entry such as a force push or a label change.
+type TimelineEvent struct {
+	Type      string    `json:"type"`
+	Actor     string    `json:"actor"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func filterByCreationDate(pullRequests []PullRequest, startTime time.Time) []PullRequest {
+	filtered := make([]PullRequest, 0)
+	sixMonthsAgo := startTime.AddDate(0, -6, 0)
+
+	for _, pr := range pullRequests {
+		if pr.CreatedAt.After(sixMonthsAgo) {
+			filtered = append(filtered, pr)
+		}
+	}
+
+	return filtered
+}
+
+// parseLinkHeader maps each rel of a Link header to its URL.
+func parseLinkHeader(header string) map[string]string {
+	links := make(map[string]string)
+	sections := strings.Split(header, ",")
+	for _, section := range sections {
+		parts := strings.Split(strings.TrimSpace(section), ";")
+		if len(parts) < 2 {
+			continue
+		}
+		urlPart := strings.Trim(strings.TrimSpace(parts[0]), "<>")
+		for _, param := range parts[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "rel=") {
+				links[strings.Trim(strings.TrimPrefix(param, "rel="), `"`)] = urlPart
+			}
+		}
+	}
+	return links
+}
+
+// GetPRComments returns the issue comments, review comments and review bodies
+// of a pull request.
+func (c *Client) GetPRComments(ctx context.Context, owner, repo string, prNumber int) ([]Comment, error) {
+	var comments []Comment
+
+	url := fmt.Sprintf("%s/repos/%s/%s/issues/%d/comments?per_page=100", c.baseURL, owner, repo, prNumber)
+	issueComments, err := getAll[Comment](ctx, c, url)
+	if err != nil {
+		return nil, err
+	}
+	for _, comment := range issueComments {
+		comment.Source = IssueComment
+		comments = append(comments, comment)
+	}
+
+	url = fmt.Sprintf("%s/repos/%s/%s/pulls/%d/comments?per_page=100", c.baseURL, owner, repo, prNumber)
+	reviewComments, err := getAll[Comment](ctx, c, url)
+	if err != nil {
+		return nil, err
+	}
+	for _, comment := range reviewComments {
+		comment.Source = ReviewComment
+		comments = append(comments, comment)
+	}
+
+	url = fmt.Sprintf("%s/repos/%s/%s/pulls/%d/reviews?per_page=100", c.baseURL, owner, repo, prNumber)
+	reviews, err := getAll[review](ctx, c, url)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range reviews {
+		// approvals without text still show up as reviews
+		if r.Body == "" {
+			continue
+		}
+		comments = append(comments, Comment{Body: r.Body, User: r.User, CreatedAt: r.SubmittedAt, Source: ReviewBody})
+	}
+
+	return comments, nil
+}
+
+// getAll follows the Link pagination of a list endpoint starting at url and
+// returns the items of every page.
+func getAll[T any](ctx context.Context, c *Client, url string) ([]T, error) {
+	var items []T
+	for url != "" {
+		resp, err := c.get(ctx, url)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("unexpected response status: %s", resp.Status)
+		}
+
+		var page []T
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page...)
+
+		url = parseLinkHeader(resp.Header.Get("Link"))["next"]
+	}
+	return items, nil
+}
+
+// CountBySource returns how many of the comments came from each source.
+func CountBySource(comments []Comment) map[CommentSource]int {
+	counts := make(map[CommentSource]int)
+	for _, comment := range comments {
+		counts[comment.Source]++
+	}
+	return counts
+}
+
+// ExtractPRInfo splits a pull request html_url into its org, repo and number.
+func ExtractPRInfo(prURL string) (string, string, int, error) {
+	// Remove the leading "https://github.com/" from the URL
+	prURL = strings.TrimPrefix(prURL, "https://github.com/")
+
+	// Split the URL path into segments
+	segments := strings.Split(prURL, "/")
+	if len(segments) < 4 {
+		return "", "", 0, fmt.Errorf("unexpected pull request URL: %s", prURL)
+	}
+
+	// Extract the organization, repository, and PR number from the segments
+	org := segments[0]
+	repo := segments[1]
+	prStr := segments[3]
+
+	// Parse the PR number as an integer
+	prNum, err := strconv.Atoi(prStr)
+	if err != nil {
+		return "", "", 0, err
+	}
+
+	return org, repo, prNum, nil
+}
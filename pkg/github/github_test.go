@@ -0,0 +1,47 @@
+package github
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractPRInfo(t *testing.T) {
+	tests := []struct {
+		url      string
+		org      string
+		repo     string
+		prNum    int
+		wantFail bool
+	}{
+		{url: "https://github.com/openshift/ovn-kubernetes/pull/1700", org: "openshift", repo: "ovn-kubernetes", prNum: 1700},
+		{url: "https://github.com/openshift/ovn-kubernetes/pull/1700/files", org: "openshift", repo: "ovn-kubernetes", prNum: 1700},
+		{url: "https://github.com/openshift/ovn-kubernetes", wantFail: true},
+		{url: "https://github.com/openshift/ovn-kubernetes/pull/abc", wantFail: true},
+	}
+	for _, tt := range tests {
+		org, repo, prNum, err := ExtractPRInfo(tt.url)
+		if tt.wantFail {
+			if err == nil {
+				t.Errorf("ExtractPRInfo(%q) succeeded, want an error", tt.url)
+			}
+			continue
+		}
+		if err != nil || org != tt.org || repo != tt.repo || prNum != tt.prNum {
+			t.Errorf("ExtractPRInfo(%q) = %s, %s, %d, %v, want %s, %s, %d", tt.url, org, repo, prNum, err, tt.org, tt.repo, tt.prNum)
+		}
+	}
+}
+
+func TestParseLinkHeader(t *testing.T) {
+	header := `<https://api.github.com/repositories/1/issues/2/comments?page=2>; rel="next", <https://api.github.com/repositories/1/issues/2/comments?page=5>; rel="last"`
+	want := map[string]string{
+		"next": "https://api.github.com/repositories/1/issues/2/comments?page=2",
+		"last": "https://api.github.com/repositories/1/issues/2/comments?page=5",
+	}
+	if got := parseLinkHeader(header); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseLinkHeader() = %v, want %v", got, want)
+	}
+	if got := parseLinkHeader(""); len(got) != 0 {
+		t.Errorf("parseLinkHeader(\"\") = %v, want no links", got)
+	}
+}
@@ -0,0 +1,462 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PRs per search page. Each PR pulls up to 100 items from several
+// connections, so larger pages run into the GraphQL node limit.
+const graphQLPageSize = 25
+
+const commentFields = `body author { login } createdAt`
+
+// connectionField is a connection of a PR, or of one of its reviews, that is
+// read 100 nodes at a time.
+type connectionField struct {
+	name string
+	// arguments besides the page ones
+	args  string
+	nodes string
+}
+
+var (
+	labelsField         = connectionField{name: "labels", nodes: `name`}
+	commentsField       = connectionField{name: "comments", nodes: commentFields}
+	reviewCommentsField = connectionField{name: "comments", nodes: commentFields}
+	reviewsField        = connectionField{name: "reviews", nodes: `id ` + commentFields + ` ` + reviewCommentsField.selection(false)}
+	commitsField        = connectionField{name: "commits", nodes: `commit { oid committedDate }`}
+	timelineItemsField  = connectionField{
+		name: "timelineItems",
+		args: `itemTypes: [HEAD_REF_FORCE_PUSHED_EVENT, LABELED_EVENT, UNLABELED_EVENT, READY_FOR_REVIEW_EVENT, REOPENED_EVENT, CLOSED_EVENT, MERGED_EVENT]`,
+		nodes: `
+      __typename
+      ... on HeadRefForcePushedEvent { createdAt actor { login } }
+      ... on LabeledEvent { createdAt actor { login } }
+      ... on UnlabeledEvent { createdAt actor { login } }
+      ... on ReadyForReviewEvent { createdAt actor { login } }
+      ... on ReopenedEvent { createdAt actor { login } }
+      ... on ClosedEvent { createdAt actor { login } }
+      ... on MergedEvent { createdAt actor { login } }`,
+	}
+)
+
+// selection returns the selection of the first page of the connection or,
+// with next, of the page after $after, aliased to conn.
+func (f connectionField) selection(next bool) string {
+	alias, args := "", "first: 100"
+	if next {
+		alias, args = "conn: ", args+", after: $after"
+	}
+	if f.args != "" {
+		args += ", " + f.args
+	}
+	return fmt.Sprintf("%s%s(%s) { pageInfo { hasNextPage endCursor } nodes { %s } }", alias, f.name, args, f.nodes)
+}
+
+// nextPRPageQuery returns the query for the page of a PR connection after
+// $after.
+func (f connectionField) nextPRPageQuery() string {
+	return `
+query($owner: String!, $name: String!, $number: Int!, $after: String) {
+  repository(owner: $owner, name: $name) {
+    pullRequest(number: $number) { ` + f.selection(true) + ` }
+  }
+}`
+}
+
+var prFields = `
+fragment prFields on PullRequest {
+  number title url createdAt updatedAt closedAt mergedAt
+  ` + labelsField.selection(false) + `
+  ` + commentsField.selection(false) + `
+  ` + reviewsField.selection(false) + `
+  ` + commitsField.selection(false) + `
+  ` + timelineItemsField.selection(false) + `
+}`
+
+// countQuery only counts the PRs a search matches, so windows over the
+// search cap are split without fetching any PR.
+const countQuery = `
+query($q: String!) {
+  search(query: $q, type: ISSUE, first: 1) { issueCount }
+}`
+
+var searchQuery = `
+query($q: String!, $first: Int!, $after: String) {
+  search(query: $q, type: ISSUE, first: $first, after: $after) {
+    issueCount
+    pageInfo { hasNextPage endCursor }
+    nodes { ... on PullRequest { ...prFields } }
+  }
+}` + prFields
+
+var pullRequestQuery = `
+query($owner: String!, $name: String!, $number: Int!) {
+  repository(owner: $owner, name: $name) {
+    pullRequest(number: $number) { ...prFields }
+  }
+}` + prFields
+
+var nextReviewCommentsQuery = `
+query($id: ID!, $after: String) {
+  node(id: $id) {
+    ... on PullRequestReview { ` + reviewCommentsField.selection(true) + ` }
+  }
+}`
+
+type gqlPageInfo struct {
+	HasNextPage bool    `json:"hasNextPage"`
+	EndCursor   *string `json:"endCursor"`
+}
+
+type gqlConnection[T any] struct {
+	PageInfo gqlPageInfo `json:"pageInfo"`
+	Nodes    []T         `json:"nodes"`
+}
+
+// gqlNextPage is the result of a query for the next page of a connection,
+// aliased to conn, of either a PR or a review node.
+type gqlNextPage[T any] struct {
+	Repository *struct {
+		PullRequest struct {
+			Conn gqlConnection[T] `json:"conn"`
+		} `json:"pullRequest"`
+	} `json:"repository"`
+	Node *struct {
+		Conn gqlConnection[T] `json:"conn"`
+	} `json:"node"`
+}
+
+type gqlActor struct {
+	Login string `json:"login"`
+}
+
+type gqlComment struct {
+	Body      string    `json:"body"`
+	Author    *gqlActor `json:"author"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type gqlReview struct {
+	ID string `json:"id"`
+	gqlComment
+	Comments gqlConnection[gqlComment] `json:"comments"`
+}
+
+type gqlCommit struct {
+	Commit struct {
+		OID           string    `json:"oid"`
+		CommittedDate time.Time `json:"committedDate"`
+	} `json:"commit"`
+}
+
+type gqlTimelineItem struct {
+	Typename  string    `json:"__typename"`
+	CreatedAt time.Time `json:"createdAt"`
+	Actor     *gqlActor `json:"actor"`
+}
+
+type gqlPullRequest struct {
+	Number        int                            `json:"number"`
+	Title         string                         `json:"title"`
+	URL           string                         `json:"url"`
+	CreatedAt     time.Time                      `json:"createdAt"`
+	UpdatedAt     time.Time                      `json:"updatedAt"`
+	ClosedAt      time.Time                      `json:"closedAt"`
+	MergedAt      *time.Time                     `json:"mergedAt"`
+	Labels        gqlConnection[Label]           `json:"labels"`
+	Comments      gqlConnection[gqlComment]      `json:"comments"`
+	Reviews       gqlConnection[gqlReview]       `json:"reviews"`
+	Commits       gqlConnection[gqlCommit]       `json:"commits"`
+	TimelineItems gqlConnection[gqlTimelineItem] `json:"timelineItems"`
+}
+
+type gqlError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// GraphQLClient is a Source that fetches closed PRs together with their
+// comments, reviews, labels, commits and timeline in batched GraphQL queries.
+// Comments of PRs returned by GetClosedPullRequests are served without
+// further requests.
+type GraphQLClient struct {
+	client *Client
+
+	mu       sync.Mutex
+	comments map[string][]Comment
+}
+
+// NewGraphQLClient returns a GraphQLClient sending its queries through client.
+func NewGraphQLClient(client *Client) *GraphQLClient {
+	return &GraphQLClient{
+		client:   client,
+		comments: make(map[string][]Comment),
+	}
+}
+
+func commentsKey(owner, repo string, prNumber int) string {
+	return fmt.Sprintf("%s/%s#%d", owner, repo, prNumber)
+}
+
+// GetClosedPullRequests returns the pull requests of owner/repo that were
+// closed between startTime and the end of the endTime day.
+func (g *GraphQLClient) GetClosedPullRequests(ctx context.Context, owner, repo string, startTime, endTime time.Time) ([]PullRequest, error) {
+	return closedPullRequests(startTime, endTime, func(from, to time.Time) ([]PullRequest, int, error) {
+		return g.searchClosed(ctx, owner, repo, closedQuery(owner, repo, from, to))
+	})
+}
+
+func (g *GraphQLClient) searchClosed(ctx context.Context, owner, repo, query string) ([]PullRequest, int, error) {
+	var count struct {
+		Search struct {
+			IssueCount int `json:"issueCount"`
+		} `json:"search"`
+	}
+	if err := g.query(ctx, countQuery, map[string]interface{}{"q": query}, &count); err != nil {
+		return nil, 0, err
+	}
+	if count.Search.IssueCount > searchResultCap {
+		// the caller splits the window, no point paging through it
+		return nil, count.Search.IssueCount, nil
+	}
+
+	var pullRequests []PullRequest
+	var after *string
+	for {
+		var data struct {
+			Search struct {
+				IssueCount int              `json:"issueCount"`
+				PageInfo   gqlPageInfo      `json:"pageInfo"`
+				Nodes      []gqlPullRequest `json:"nodes"`
+			} `json:"search"`
+		}
+		variables := map[string]interface{}{"q": query, "first": graphQLPageSize, "after": after}
+		if err := g.query(ctx, searchQuery, variables, &data); err != nil {
+			return nil, 0, err
+		}
+		if data.Search.IssueCount > searchResultCap {
+			// PRs closed since the count pushed the window over the cap
+			return nil, data.Search.IssueCount, nil
+		}
+		for _, node := range data.Search.Nodes {
+			pr, err := g.convert(ctx, owner, repo, node)
+			if err != nil {
+				return nil, 0, err
+			}
+			pullRequests = append(pullRequests, pr)
+		}
+		if !data.Search.PageInfo.HasNextPage {
+			return pullRequests, data.Search.IssueCount, nil
+		}
+		after = data.Search.PageInfo.EndCursor
+	}
+}
+
+// GetPRComments returns the issue comments, review bodies and review comments
+// of a pull request.
+func (g *GraphQLClient) GetPRComments(ctx context.Context, owner, repo string, prNumber int) ([]Comment, error) {
+	g.mu.Lock()
+	comments, ok := g.comments[commentsKey(owner, repo, prNumber)]
+	g.mu.Unlock()
+	if ok {
+		return comments, nil
+	}
+
+	var data struct {
+		Repository struct {
+			PullRequest *gqlPullRequest `json:"pullRequest"`
+		} `json:"repository"`
+	}
+	variables := map[string]interface{}{"owner": owner, "name": repo, "number": prNumber}
+	if err := g.query(ctx, pullRequestQuery, variables, &data); err != nil {
+		return nil, err
+	}
+	if data.Repository.PullRequest == nil {
+		return nil, fmt.Errorf("pull request %s not found", commentsKey(owner, repo, prNumber))
+	}
+	if _, err := g.convert(ctx, owner, repo, *data.Repository.PullRequest); err != nil {
+		return nil, err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.comments[commentsKey(owner, repo, prNumber)], nil
+}
+
+// convert turns a queried PR into a PullRequest, fetching the nodes of every
+// connection that did not fit in its first page and caching the PR's
+// comments.
+func (g *GraphQLClient) convert(ctx context.Context, owner, repo string, node gqlPullRequest) (PullRequest, error) {
+	pr := PullRequest{
+		Number:    node.Number,
+		Title:     node.Title,
+		URL:       node.URL,
+		CreatedAt: node.CreatedAt,
+		UpdatedAt: node.UpdatedAt,
+		ClosedAt:  node.ClosedAt,
+		MergedAt:  node.MergedAt,
+	}
+	prVariables := func() map[string]interface{} {
+		return map[string]interface{}{"owner": owner, "name": repo, "number": node.Number}
+	}
+
+	labels, err := allNodes(ctx, g, node.Labels, labelsField.nextPRPageQuery(), prVariables())
+	if err != nil {
+		return pr, err
+	}
+	pr.Labels = labels
+
+	commits, err := allNodes(ctx, g, node.Commits, commitsField.nextPRPageQuery(), prVariables())
+	if err != nil {
+		return pr, err
+	}
+	for _, c := range commits {
+		pr.Commits = append(pr.Commits, Commit{SHA: c.Commit.OID, CommittedDate: c.Commit.CommittedDate})
+	}
+
+	timelineItems, err := allNodes(ctx, g, node.TimelineItems, timelineItemsField.nextPRPageQuery(), prVariables())
+	if err != nil {
+		return pr, err
+	}
+	for _, e := range timelineItems {
+		event := TimelineEvent{Type: e.Typename, CreatedAt: e.CreatedAt}
+		if e.Actor != nil {
+			event.Actor = e.Actor.Login
+		}
+		pr.TimelineEvents = append(pr.TimelineEvents, event)
+	}
+
+	issueComments, err := allNodes(ctx, g, node.Comments, commentsField.nextPRPageQuery(), prVariables())
+	if err != nil {
+		return pr, err
+	}
+	reviews, err := allNodes(ctx, g, node.Reviews, reviewsField.nextPRPageQuery(), prVariables())
+	if err != nil {
+		return pr, err
+	}
+
+	var comments []Comment
+	for _, c := range issueComments {
+		comments = append(comments, c.toComment(IssueComment))
+	}
+	for _, r := range reviews {
+		if r.Body != "" {
+			comments = append(comments, r.toComment(ReviewBody))
+		}
+		reviewComments, err := allNodes(ctx, g, r.Comments, nextReviewCommentsQuery, map[string]interface{}{"id": r.ID})
+		if err != nil {
+			return pr, err
+		}
+		for _, c := range reviewComments {
+			comments = append(comments, c.toComment(ReviewComment))
+		}
+	}
+
+	g.mu.Lock()
+	g.comments[commentsKey(owner, repo, node.Number)] = comments
+	g.mu.Unlock()
+
+	return pr, nil
+}
+
+// allNodes returns the nodes of conn, fetching the pages after the first one
+// with query, which selects the next page of the connection as conn.
+func allNodes[T any](ctx context.Context, g *GraphQLClient, conn gqlConnection[T], query string, variables map[string]interface{}) ([]T, error) {
+	nodes := conn.Nodes
+	pageInfo := conn.PageInfo
+	for pageInfo.HasNextPage {
+		if pageInfo.EndCursor == nil {
+			return nil, fmt.Errorf("graphql: connection has more pages but no end cursor")
+		}
+		variables["after"] = *pageInfo.EndCursor
+		var data gqlNextPage[T]
+		if err := g.query(ctx, query, variables, &data); err != nil {
+			return nil, err
+		}
+		var next gqlConnection[T]
+		switch {
+		case data.Repository != nil:
+			next = data.Repository.PullRequest.Conn
+		case data.Node != nil:
+			next = data.Node.Conn
+		default:
+			return nil, fmt.Errorf("graphql: next page query returned no data")
+		}
+		if next.PageInfo.HasNextPage && len(next.Nodes) == 0 {
+			return nil, fmt.Errorf("graphql: empty page of a connection with more pages")
+		}
+		nodes = append(nodes, next.Nodes...)
+		pageInfo = next.PageInfo
+	}
+	return nodes, nil
+}
+
+func (c gqlComment) toComment(source CommentSource) Comment {
+	comment := Comment{Body: c.Body, CreatedAt: c.CreatedAt, Source: source}
+	if c.Author != nil {
+		comment.User.Login = c.Author.Login
+	}
+	return comment
+}
+
+// graphQLURL returns the GraphQL endpoint of the API the client talks to.
+// GitHub Enterprise serves REST under /api/v3 and GraphQL at /api/graphql.
+func (c *Client) graphQLURL() string {
+	if strings.HasSuffix(c.baseURL, "/api/v3") {
+		return strings.TrimSuffix(c.baseURL, "/v3") + "/graphql"
+	}
+	return c.baseURL + "/graphql"
+}
+
+// query runs a GraphQL query and decodes its data into data, waiting and
+// retrying when the query is rate limited.
+func (g *GraphQLClient) query(ctx context.Context, query string, variables map[string]interface{}, data interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := g.client.do(ctx, "POST", g.client.graphQLURL(), body)
+		if err != nil {
+			return err
+		}
+
+		var result struct {
+			Data   json.RawMessage `json:"data"`
+			Errors []gqlError      `json:"errors"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("unexpected response status: %s", resp.Status)
+		}
+		if err != nil {
+			return err
+		}
+
+		if len(result.Errors) > 0 {
+			if result.Errors[0].Type == "RATE_LIMITED" && attempt < maxRateLimitRetries {
+				// do has recorded the reset time when the primary limit ran out
+				if resp.Header.Get("X-RateLimit-Remaining") != "0" {
+					log.Printf("GitHub GraphQL rate limit hit, waiting %s before retrying", secondaryRateLimitWait<<attempt)
+					if err := sleep(ctx, secondaryRateLimitWait<<attempt); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			return fmt.Errorf("graphql: %s", result.Errors[0].Message)
+		}
+
+		return json.Unmarshal(result.Data, data)
+	}
+}
@@ -0,0 +1,347 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeGraphQL is a GitHub GraphQL server holding a few PRs. It serves every
+// connection two nodes at a time, so clients have to page through them.
+type fakeGraphQL struct {
+	t   *testing.T
+	prs []fakePR
+	// when set, the issueCount reported for a search window
+	issueCount func(from, to time.Time, count int) int
+
+	mu       sync.Mutex
+	requests int
+	// searches that asked for PR fields of a window over the search cap
+	overCapFetches int
+}
+
+type fakeComment struct {
+	body, author string
+}
+
+type fakeReview struct {
+	id       string
+	body     string
+	comments []fakeComment
+}
+
+type fakePR struct {
+	number   int
+	closedAt time.Time
+	labels   []string
+	comments []fakeComment
+	reviews  []fakeReview
+	commits  []string
+	events   []string
+}
+
+const fakePageSize = 2
+
+var (
+	closedRange = regexp.MustCompile(`closed:(\S+)\.\.(\S+)`)
+	nextConn    = regexp.MustCompile(`conn: (\w+)\(`)
+)
+
+func (f *fakeGraphQL) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.requests++
+	f.mu.Unlock()
+
+	if r.Method != "POST" || r.URL.Path != "/api/graphql" {
+		http.NotFound(w, r)
+		return
+	}
+	var req struct {
+		Query     string                 `json:"query"`
+		Variables map[string]interface{} `json:"variables"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		f.t.Errorf("fake GraphQL server got an invalid request: %v", err)
+		return
+	}
+	after, _ := req.Variables["after"].(string)
+
+	var data interface{}
+	switch {
+	case strings.Contains(req.Query, "node(id:"):
+		review := f.review(req.Variables["id"].(string))
+		data = map[string]interface{}{"node": map[string]interface{}{"conn": page(commentNodes(review.comments), after)}}
+	case nextConn.MatchString(req.Query):
+		pr := f.pr(int(req.Variables["number"].(float64)))
+		conn := f.connections(pr)[nextConn.FindStringSubmatch(req.Query)[1]]
+		data = map[string]interface{}{"repository": map[string]interface{}{"pullRequest": map[string]interface{}{"conn": page(conn, after)}}}
+	case strings.Contains(req.Query, "pullRequest(number:"):
+		pr := f.pr(int(req.Variables["number"].(float64)))
+		data = map[string]interface{}{"repository": map[string]interface{}{"pullRequest": f.prNode(pr)}}
+	case strings.Contains(req.Query, "search("):
+		data = map[string]interface{}{"search": f.search(req.Query, req.Variables["q"].(string), after)}
+	default:
+		f.t.Errorf("fake GraphQL server got an unexpected query: %s", req.Query)
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+}
+
+func (f *fakeGraphQL) search(query, q, after string) map[string]interface{} {
+	match := closedRange.FindStringSubmatch(q)
+	from, _ := time.Parse(searchTimeFmt, match[1])
+	to, _ := time.Parse(searchTimeFmt, match[2])
+	var nodes []interface{}
+	for _, pr := range f.prs {
+		if !pr.closedAt.Before(from) && !pr.closedAt.After(to) {
+			nodes = append(nodes, f.prNode(pr))
+		}
+	}
+	count := len(nodes)
+	if f.issueCount != nil {
+		count = f.issueCount(from, to, count)
+	}
+	result := map[string]interface{}{"issueCount": count}
+	if !strings.Contains(query, "prFields") {
+		return result
+	}
+	if count > searchResultCap {
+		f.mu.Lock()
+		f.overCapFetches++
+		f.mu.Unlock()
+	}
+	p := page(nodes, after)
+	result["pageInfo"], result["nodes"] = p["pageInfo"], p["nodes"]
+	return result
+}
+
+func (f *fakeGraphQL) pr(number int) fakePR {
+	for _, pr := range f.prs {
+		if pr.number == number {
+			return pr
+		}
+	}
+	f.t.Fatalf("fake GraphQL server has no PR %d", number)
+	return fakePR{}
+}
+
+func (f *fakeGraphQL) review(id string) fakeReview {
+	for _, pr := range f.prs {
+		for _, r := range pr.reviews {
+			if r.id == id {
+				return r
+			}
+		}
+	}
+	f.t.Fatalf("fake GraphQL server has no review %s", id)
+	return fakeReview{}
+}
+
+func (f *fakeGraphQL) connections(pr fakePR) map[string][]interface{} {
+	conns := map[string][]interface{}{}
+	for _, label := range pr.labels {
+		conns["labels"] = append(conns["labels"], map[string]interface{}{"name": label})
+	}
+	conns["comments"] = commentNodes(pr.comments)
+	for _, r := range pr.reviews {
+		conns["reviews"] = append(conns["reviews"], map[string]interface{}{
+			"id":        r.id,
+			"body":      r.body,
+			"author":    map[string]interface{}{"login": "reviewer"},
+			"createdAt": "2023-07-01T00:00:00Z",
+			"comments":  page(commentNodes(r.comments), ""),
+		})
+	}
+	for _, sha := range pr.commits {
+		conns["commits"] = append(conns["commits"], map[string]interface{}{
+			"commit": map[string]interface{}{"oid": sha, "committedDate": "2023-07-01T00:00:00Z"},
+		})
+	}
+	for _, event := range pr.events {
+		conns["timelineItems"] = append(conns["timelineItems"], map[string]interface{}{
+			"__typename": event, "createdAt": "2023-07-01T00:00:00Z", "actor": map[string]interface{}{"login": "someone"},
+		})
+	}
+	return conns
+}
+
+func (f *fakeGraphQL) prNode(pr fakePR) map[string]interface{} {
+	node := map[string]interface{}{
+		"number":    pr.number,
+		"title":     fmt.Sprintf("PR %d", pr.number),
+		"url":       fmt.Sprintf("https://github.com/openshift/ovn-kubernetes/pull/%d", pr.number),
+		"createdAt": pr.closedAt.Add(-time.Hour).Format(time.RFC3339),
+		"updatedAt": pr.closedAt.Format(time.RFC3339),
+		"closedAt":  pr.closedAt.Format(time.RFC3339),
+	}
+	for name, conn := range f.connections(pr) {
+		node[name] = page(conn, "")
+	}
+	return node
+}
+
+func commentNodes(comments []fakeComment) []interface{} {
+	var nodes []interface{}
+	for _, c := range comments {
+		nodes = append(nodes, map[string]interface{}{
+			"body": c.body, "author": map[string]interface{}{"login": c.author}, "createdAt": "2023-07-01T00:00:00Z",
+		})
+	}
+	return nodes
+}
+
+// page returns the page of nodes after the cursor, which is the offset of
+// the page's first node.
+func page(nodes []interface{}, after string) map[string]interface{} {
+	offset, _ := strconv.Atoi(after)
+	end := offset + fakePageSize
+	if end > len(nodes) {
+		end = len(nodes)
+	}
+	pageNodes := []interface{}{}
+	if offset < len(nodes) {
+		pageNodes = nodes[offset:end]
+	}
+	return map[string]interface{}{
+		"pageInfo": map[string]interface{}{"hasNextPage": end < len(nodes), "endCursor": strconv.Itoa(end)},
+		"nodes":    pageNodes,
+	}
+}
+
+func newFakeGraphQL(t *testing.T, f *fakeGraphQL) *GraphQLClient {
+	t.Helper()
+	f.t = t
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	client := NewClient("token")
+	// a GitHub Enterprise API root, whose GraphQL endpoint is /api/graphql
+	client.SetBaseURL(server.URL + "/api/v3")
+	return NewGraphQLClient(client)
+}
+
+func fakeComments(n int, prefix string) []fakeComment {
+	comments := make([]fakeComment, n)
+	for i := range comments {
+		comments[i] = fakeComment{body: fmt.Sprintf("%s %d", prefix, i), author: "someone"}
+	}
+	return comments
+}
+
+func TestGraphQLPagesEveryConnection(t *testing.T) {
+	closed := time.Date(2023, 7, 3, 12, 0, 0, 0, time.UTC)
+	f := &fakeGraphQL{}
+	for i := 1; i <= 3; i++ {
+		f.prs = append(f.prs, fakePR{
+			number:   i,
+			closedAt: closed.Add(time.Duration(i) * time.Hour),
+			labels:   []string{"lgtm", "approved", "jira/valid-bug", "size/L", "ok-to-test"},
+			comments: append(fakeComments(4, "comment"), fakeComment{body: "/retest", author: "someone"}),
+			reviews: []fakeReview{
+				{id: fmt.Sprintf("r%d-1", i), body: "looks good", comments: fakeComments(5, "nit")},
+				{id: fmt.Sprintf("r%d-2", i)},
+				{id: fmt.Sprintf("r%d-3", i), body: "/retest"},
+			},
+			commits: []string{"a", "b", "c"},
+			events:  []string{"HeadRefForcePushedEvent", "LabeledEvent", "ClosedEvent"},
+		})
+	}
+	g := newFakeGraphQL(t, f)
+	ctx := context.Background()
+
+	prs, err := g.GetClosedPullRequests(ctx, "openshift", "ovn-kubernetes", closed, closed)
+	if err != nil {
+		t.Fatalf("GetClosedPullRequests() failed: %v", err)
+	}
+	if len(prs) != 3 {
+		t.Fatalf("got %d PRs, want 3", len(prs))
+	}
+	for _, pr := range prs {
+		if len(pr.Labels) != 5 || len(pr.Commits) != 3 || len(pr.TimelineEvents) != 3 {
+			t.Errorf("PR %d has %d labels, %d commits and %d events, want 5, 3 and 3",
+				pr.Number, len(pr.Labels), len(pr.Commits), len(pr.TimelineEvents))
+		}
+	}
+
+	f.mu.Lock()
+	before := f.requests
+	f.mu.Unlock()
+	comments, err := g.GetPRComments(ctx, "openshift", "ovn-kubernetes", 2)
+	if err != nil {
+		t.Fatalf("GetPRComments() failed: %v", err)
+	}
+	want := map[CommentSource]int{IssueComment: 5, ReviewBody: 2, ReviewComment: 5}
+	got := CountBySource(comments)
+	for source, n := range want {
+		if got[source] != n {
+			t.Errorf("got %d %s comments, want %d", got[source], source, n)
+		}
+	}
+	f.mu.Lock()
+	after := f.requests
+	f.mu.Unlock()
+	if after != before {
+		t.Errorf("GetPRComments() of a searched PR sent %d requests, want none", after-before)
+	}
+
+	// a PR that was not searched for is queried on its own
+	g = newFakeGraphQL(t, f)
+	comments, err = g.GetPRComments(ctx, "openshift", "ovn-kubernetes", 3)
+	if err != nil {
+		t.Fatalf("GetPRComments() failed: %v", err)
+	}
+	if len(comments) != 12 {
+		t.Errorf("got %d comments of an unsearched PR, want 12", len(comments))
+	}
+}
+
+func TestGraphQLSplitsWithoutFetchingOverCap(t *testing.T) {
+	start := time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+	f := &fakeGraphQL{
+		prs: []fakePR{
+			{number: 1, closedAt: start.Add(time.Hour)},
+			{number: 2, closedAt: start.Add(50 * time.Hour)},
+		},
+		// pretend windows longer than a day hold too many PRs
+		issueCount: func(from, to time.Time, count int) int {
+			if to.Sub(from) > 24*time.Hour {
+				return searchResultCap + 1
+			}
+			return count
+		},
+	}
+	g := newFakeGraphQL(t, f)
+
+	prs, err := g.GetClosedPullRequests(context.Background(), "openshift", "ovn-kubernetes", start, start.AddDate(0, 0, 3))
+	if err != nil {
+		t.Fatalf("GetClosedPullRequests() failed: %v", err)
+	}
+	if len(prs) != 2 {
+		t.Errorf("got %d PRs, want 2", len(prs))
+	}
+	if f.overCapFetches != 0 {
+		t.Errorf("fetched PR fields of %d windows over the search cap", f.overCapFetches)
+	}
+}
+
+func TestGraphQLURL(t *testing.T) {
+	tests := map[string]string{
+		"https://api.github.com":             "https://api.github.com/graphql",
+		"https://github.example.com/api/v3":  "https://github.example.com/api/graphql",
+		"https://github.example.com/api/v3/": "https://github.example.com/api/graphql",
+		"http://127.0.0.1:8080/fake/":        "http://127.0.0.1:8080/fake/graphql",
+	}
+	for baseURL, want := range tests {
+		c := NewClient("")
+		c.SetBaseURL(baseURL)
+		if got := c.graphQLURL(); got != want {
+			t.Errorf("graphQLURL() with base %s = %s, want %s", baseURL, got, want)
+		}
+	}
+}
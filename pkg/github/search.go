@@ -0,0 +1,180 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+)
+
+const (
+	// the search APIs stop returning items after this many results
+	searchResultCap = 1000
+	searchPerPage   = 100
+	searchTimeFmt   = "2006-01-02T15:04:05Z"
+	// how many times a window whose total changed while it was paged
+	// through is searched again before it is split
+	maxWindowRetries = 2
+)
+
+// windowSearch returns the total number of PRs closed within [from, to] and,
+// when that total is within searchResultCap, all of them.
+type windowSearch func(from, to time.Time) ([]PullRequest, int, error)
+
+// closedPullRequests runs search over the days from startTime to endTime and
+// returns the deduplicated PRs in the order they were closed.
+func closedPullRequests(startTime, endTime time.Time, search windowSearch) ([]PullRequest, error) {
+	from := startTime.UTC().Truncate(24 * time.Hour)
+	to := endTime.UTC().Truncate(24*time.Hour).AddDate(0, 0, 1).Add(-time.Second)
+
+	pullRequests, err := searchWindow(from, to, search)
+	if err != nil {
+		return nil, err
+	}
+
+	// windows don't overlap, but a PR reopened and closed again while paging
+	// could show up in two of them
+	seen := make(map[string]bool)
+	deduped := make([]PullRequest, 0, len(pullRequests))
+	for _, pr := range pullRequests {
+		if seen[pr.URL] {
+			continue
+		}
+		seen[pr.URL] = true
+		deduped = append(deduped, pr)
+	}
+	sort.Slice(deduped, func(i, j int) bool {
+		return deduped[i].ClosedAt.Before(deduped[j].ClosedAt)
+	})
+
+	return filterByCreationDate(deduped, startTime), nil
+}
+
+// searchWindow returns every PR closed within [from, to], bisecting the window
+// while the search reports more results than it can return. PRs that close
+// while a window is paged through, which happens to windows ending now,
+// change its total; such a window is searched again and, if it keeps
+// changing, split into smaller ones that are paged through faster.
+func searchWindow(from, to time.Time, search windowSearch) ([]PullRequest, error) {
+	for attempt := 0; ; attempt++ {
+		pullRequests, total, err := search(from, to)
+		if err != nil {
+			return nil, err
+		}
+
+		if total > searchResultCap {
+			return splitWindow(from, to, search, fmt.Errorf("%d PRs closed within %s..%s, window cannot be split further", total, from.Format(searchTimeFmt), to.Format(searchTimeFmt)))
+		}
+		if len(pullRequests) == total {
+			return pullRequests, nil
+		}
+
+		mismatch := fmt.Errorf("search for PRs closed within %s..%s returned %d of %d PRs", from.Format(searchTimeFmt), to.Format(searchTimeFmt), len(pullRequests), total)
+		if attempt < maxWindowRetries {
+			log.Printf("%v, searching the window again", mismatch)
+			continue
+		}
+		return splitWindow(from, to, search, mismatch)
+	}
+}
+
+// splitWindow searches the two halves of [from, to], or returns err when the
+// window is too short to split.
+func splitWindow(from, to time.Time, search windowSearch, err error) ([]PullRequest, error) {
+	if to.Sub(from) < 2*time.Second {
+		return nil, err
+	}
+	mid := from.Add(to.Sub(from) / 2).Truncate(time.Second)
+	left, err := searchWindow(from, mid, search)
+	if err != nil {
+		return nil, err
+	}
+	right, err := searchWindow(mid.Add(time.Second), to, search)
+	if err != nil {
+		return nil, err
+	}
+	return append(left, right...), nil
+}
+
+func closedQuery(owner, repo string, from, to time.Time) string {
+	return fmt.Sprintf("repo:%s/%s is:pr is:closed closed:%s..%s", owner, repo, from.Format(searchTimeFmt), to.Format(searchTimeFmt))
+}
+
+type searchItem struct {
+	PullRequest
+	PullRequestRef struct {
+		MergedAt *time.Time `json:"merged_at"`
+	} `json:"pull_request"`
+}
+
+type searchResult struct {
+	TotalCount        int          `json:"total_count"`
+	IncompleteResults bool         `json:"incomplete_results"`
+	Items             []searchItem `json:"items"`
+}
+
+// GetClosedPullRequests returns the pull requests of owner/repo that were
+// closed between startTime and the end of the endTime day. Ranges holding more
+// results than the search API returns are split until every window fits.
+func (c *Client) GetClosedPullRequests(ctx context.Context, owner, repo string, startTime, endTime time.Time) ([]PullRequest, error) {
+	return closedPullRequests(startTime, endTime, func(from, to time.Time) ([]PullRequest, int, error) {
+		return c.searchClosed(ctx, closedQuery(owner, repo, from, to))
+	})
+}
+
+func (c *Client) searchClosed(ctx context.Context, query string) ([]PullRequest, int, error) {
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("per_page", fmt.Sprint(searchPerPage))
+	nextURL := c.baseURL + "/search/issues?" + params.Encode()
+
+	var pullRequests []PullRequest
+	total := 0
+	for nextURL != "" {
+		var result searchResult
+		var err error
+		result, nextURL, err = c.searchPage(ctx, nextURL)
+		if err != nil {
+			return nil, 0, err
+		}
+		if result.IncompleteResults {
+			return nil, 0, fmt.Errorf("search for %q timed out with incomplete results", query)
+		}
+		total = result.TotalCount
+		if total > searchResultCap {
+			// the caller splits the window, no point paging through it
+			return nil, total, nil
+		}
+		for _, item := range result.Items {
+			pr := item.PullRequest
+			pr.MergedAt = item.PullRequestRef.MergedAt
+			pullRequests = append(pullRequests, pr)
+		}
+	}
+	return pullRequests, total, nil
+}
+
+// searchPage fetches one page of search results and the URL of the next one.
+func (c *Client) searchPage(ctx context.Context, pageURL string) (searchResult, string, error) {
+	var result searchResult
+
+	resp, err := c.get(ctx, pageURL)
+	if err != nil {
+		return result, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return result, "", fmt.Errorf("unexpected response status: %s", resp.Status)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return result, "", err
+	}
+
+	return result, parseLinkHeader(resp.Header.Get("Link"))["next"], nil
+}
@@ -0,0 +1,139 @@
+package github
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// fakeSearch serves windowSearch calls from a fixed set of closed PRs.
+type fakeSearch struct {
+	prs   []PullRequest
+	calls int
+	// when set, changes the results of a call before they are returned,
+	// such as to make a PR close while the window is paged through
+	tamper func(call int, from, to time.Time, prs []PullRequest, total int) ([]PullRequest, int)
+}
+
+func (f *fakeSearch) search(from, to time.Time) ([]PullRequest, int, error) {
+	f.calls++
+	var matched []PullRequest
+	for _, pr := range f.prs {
+		if !pr.ClosedAt.Before(from) && !pr.ClosedAt.After(to) {
+			matched = append(matched, pr)
+		}
+	}
+	total := len(matched)
+	if total > searchResultCap {
+		matched = nil
+	}
+	if f.tamper != nil {
+		matched, total = f.tamper(f.calls, from, to, matched, total)
+	}
+	return matched, total, nil
+}
+
+func closedPRs(n int, start time.Time, every time.Duration) []PullRequest {
+	prs := make([]PullRequest, n)
+	for i := range prs {
+		closed := start.Add(time.Duration(i) * every)
+		prs[i] = PullRequest{
+			Number:    i + 1,
+			URL:       fmt.Sprintf("https://github.com/openshift/ovn-kubernetes/pull/%d", i+1),
+			CreatedAt: closed.Add(-time.Hour),
+			ClosedAt:  closed,
+		}
+	}
+	return prs
+}
+
+func checkComplete(t *testing.T, got, want []PullRequest) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d PRs, want %d", len(got), len(want))
+	}
+	for i := range got {
+		if got[i].URL != want[i].URL {
+			t.Fatalf("PR %d is %s, want %s", i, got[i].URL, want[i].URL)
+		}
+	}
+}
+
+var (
+	searchStart = time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+	searchEnd   = time.Date(2023, 7, 10, 0, 0, 0, 0, time.UTC)
+)
+
+func TestClosedPullRequestsBisects(t *testing.T) {
+	prs := closedPRs(2500, searchStart, 5*time.Minute)
+	f := &fakeSearch{prs: prs}
+	got, err := closedPullRequests(searchStart, searchEnd, f.search)
+	if err != nil {
+		t.Fatalf("closedPullRequests() failed: %v", err)
+	}
+	checkComplete(t, got, prs)
+	if f.calls < 3 {
+		t.Errorf("searched %d windows, expected the range to be split", f.calls)
+	}
+}
+
+func TestClosedPullRequestsDedupes(t *testing.T) {
+	prs := closedPRs(10, searchStart, time.Hour)
+	f := &fakeSearch{prs: prs}
+	// a PR reopened and closed again while paging shows up twice
+	f.tamper = func(call int, from, to time.Time, matched []PullRequest, total int) ([]PullRequest, int) {
+		return append(matched, matched[0]), total + 1
+	}
+	got, err := closedPullRequests(searchStart, searchEnd, f.search)
+	if err != nil {
+		t.Fatalf("closedPullRequests() failed: %v", err)
+	}
+	checkComplete(t, got, prs)
+}
+
+func TestClosedPullRequestsTotalChanges(t *testing.T) {
+	prs := closedPRs(10, searchStart, time.Hour)
+	f := &fakeSearch{prs: prs}
+	// a PR closes after the first page was read, so the total is one more
+	// than the PRs returned
+	f.tamper = func(call int, from, to time.Time, matched []PullRequest, total int) ([]PullRequest, int) {
+		if call == 1 {
+			return matched, total + 1
+		}
+		return matched, total
+	}
+	got, err := closedPullRequests(searchStart, searchEnd, f.search)
+	if err != nil {
+		t.Fatalf("closedPullRequests() failed: %v", err)
+	}
+	checkComplete(t, got, prs)
+	if f.calls != 2 {
+		t.Errorf("searched %d times, want the window searched again once", f.calls)
+	}
+}
+
+func TestClosedPullRequestsKeepsChanging(t *testing.T) {
+	prs := closedPRs(10, searchStart, time.Hour)
+	f := &fakeSearch{prs: prs}
+	// windows longer than a day keep changing while they are paged through
+	f.tamper = func(call int, from, to time.Time, matched []PullRequest, total int) ([]PullRequest, int) {
+		if to.Sub(from) > 24*time.Hour && len(matched) > 0 {
+			return matched[1:], total
+		}
+		return matched, total
+	}
+	got, err := closedPullRequests(searchStart, searchEnd, f.search)
+	if err != nil {
+		t.Fatalf("closedPullRequests() failed: %v", err)
+	}
+	checkComplete(t, got, prs)
+}
+
+func TestClosedPullRequestsCannotSplit(t *testing.T) {
+	// more PRs than the cap closing within the same second
+	prs := closedPRs(searchResultCap+1, searchStart, 0)
+	f := &fakeSearch{prs: prs}
+	if _, err := closedPullRequests(searchStart, searchStart, f.search); err == nil {
+		t.Errorf("closedPullRequests() succeeded with %d PRs closed in one second", len(prs))
+	}
+}
@@ -0,0 +1,323 @@
+// Package httpcache is an on-disk cache of raw HTTP responses shared by the
+// GitHub and Prow clients. Finished job artifacts are cached for good, Prow
+// history pages for a limited time, and anything served with an ETag or
+// Last-Modified header is revalidated with a conditional request, which
+// GitHub does not count against the rate limit when it answers 304.
+package httpcache
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Kind tells how a cached response is kept fresh.
+type Kind string
+
+const (
+	// the response never changes, such as the finished.json of a run
+	KindImmutable = Kind("immutable")
+	// the response is served until it is older than the cache TTL, such as a
+	// pr-history page
+	KindTTL = Kind("ttl")
+	// the response is revalidated with its ETag or Last-Modified header on
+	// every use
+	KindRevalidate = Kind("revalidate")
+)
+
+// immutableArtifacts are the job run artifacts that are written once and
+// never change afterwards.
+var immutableArtifacts = []string{
+	"/started.json",
+	"/finished.json",
+	"/ci-operator-step-graph.json",
+}
+
+// Entry is a cached response as stored on disk.
+type Entry struct {
+	URL        string
+	Kind       Kind
+	StoredAt   time.Time
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Stats counts how requests were served.
+type Stats struct {
+	// served from the cache without a request
+	Hits int64
+	// revalidated with a conditional request that returned 304
+	Revalidated int64
+	// fetched from the server
+	Misses int64
+}
+
+// Cache is an http.RoundTripper that caches GET responses in a directory.
+type Cache struct {
+	Dir string
+	// how long history pages are served from the cache
+	TTL time.Duration
+	// the transport requests are sent with, http.DefaultTransport when nil
+	Transport http.RoundTripper
+
+	hits, revalidated, misses int64
+}
+
+// New returns a Cache storing responses in dir.
+func New(dir string, ttl time.Duration) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %v", err)
+	}
+	return &Cache{Dir: dir, TTL: ttl}, nil
+}
+
+// DefaultDir returns the directory the cache is kept in unless one is given.
+func DefaultDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ".prstats-cache"
+	}
+	return filepath.Join(dir, "prstats")
+}
+
+// Client returns an http.Client that sends its requests through the cache.
+func (c *Cache) Client() *http.Client {
+	return &http.Client{Transport: c}
+}
+
+// Stats returns how the requests made so far were served.
+func (c *Cache) Stats() Stats {
+	return Stats{
+		Hits:        atomic.LoadInt64(&c.hits),
+		Revalidated: atomic.LoadInt64(&c.revalidated),
+		Misses:      atomic.LoadInt64(&c.misses),
+	}
+}
+
+func (c *Cache) transport() http.RoundTripper {
+	if c.Transport != nil {
+		return c.Transport
+	}
+	return http.DefaultTransport
+}
+
+// RoundTrip serves GET requests from the cache when possible and stores the
+// successful responses it fetches.
+func (c *Cache) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return c.transport().RoundTrip(req)
+	}
+
+	key := Key(req)
+	entry, _ := c.load(key)
+	if entry != nil && c.fresh(entry) {
+		atomic.AddInt64(&c.hits, 1)
+		resp := entry.response(req)
+		// rate limit headers stored with the entry are stale
+		for name := range resp.Header {
+			if strings.HasPrefix(name, "X-Ratelimit-") {
+				resp.Header.Del(name)
+			}
+		}
+		return resp, nil
+	}
+
+	outReq := req
+	if entry != nil {
+		outReq = req.Clone(req.Context())
+		if etag := entry.Header.Get("ETag"); etag != "" {
+			outReq.Header.Set("If-None-Match", etag)
+		}
+		if modified := entry.Header.Get("Last-Modified"); modified != "" {
+			outReq.Header.Set("If-Modified-Since", modified)
+		}
+	}
+
+	resp, err := c.transport().RoundTrip(outReq)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		resp.Body.Close()
+		atomic.AddInt64(&c.revalidated, 1)
+		// the 304 carries the current rate limit headers
+		for name, values := range resp.Header {
+			entry.Header[name] = values
+		}
+		entry.StoredAt = time.Now()
+		c.save(key, entry)
+		return entry.response(req), nil
+	}
+	atomic.AddInt64(&c.misses, 1)
+
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	kind, ok := c.kind(req, resp.Header, body)
+	if ok {
+		c.save(key, &Entry{
+			URL:        req.URL.String(),
+			Kind:       kind,
+			StoredAt:   time.Now(),
+			StatusCode: resp.StatusCode,
+			Header:     resp.Header,
+			Body:       body,
+		})
+	}
+	return resp, nil
+}
+
+// kind decides how a response is cached, if at all.
+func (c *Cache) kind(req *http.Request, header http.Header, body []byte) (Kind, bool) {
+	path := req.URL.Path
+	for _, suffix := range immutableArtifacts {
+		if strings.HasSuffix(path, suffix) {
+			return KindImmutable, true
+		}
+	}
+	// prowjob.json is updated until the run completes
+	if strings.HasSuffix(path, "/prowjob.json") && bytes.Contains(body, []byte(`"completionTime"`)) {
+		return KindImmutable, true
+	}
+	if header.Get("ETag") != "" || header.Get("Last-Modified") != "" {
+		return KindRevalidate, true
+	}
+	if strings.Contains(path, "/pr-history") || strings.Contains(path, "/job-history/") {
+		return KindTTL, c.TTL > 0
+	}
+	return "", false
+}
+
+func (c *Cache) fresh(entry *Entry) bool {
+	switch entry.Kind {
+	case KindImmutable:
+		return true
+	case KindTTL:
+		return time.Since(entry.StoredAt) < c.TTL
+	}
+	return false
+}
+
+// Expired reports whether a history page entry has outlived the TTL. Other
+// entries stay useful: immutable ones for good and revalidated ones for
+// their validators.
+func (c *Cache) Expired(entry *Entry) bool {
+	return entry.Kind == KindTTL && !c.fresh(entry)
+}
+
+func (e *Entry) response(req *http.Request) *http.Response {
+	header := e.Header.Clone()
+	header.Set("X-Cache", "hit")
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// Key returns the cache key of a request. GitHub answers differently
+// depending on who asks, so the credentials are part of the key.
+func Key(req *http.Request) string {
+	h := sha256.New()
+	io.WriteString(h, req.URL.String())
+	io.WriteString(h, "\n")
+	io.WriteString(h, req.Header.Get("Authorization"))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.Dir, key[:2], key+".json")
+}
+
+func (c *Cache) load(key string) (*Entry, error) {
+	return readEntry(c.path(key))
+}
+
+func readEntry(path string) (*Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entry Entry
+	if err := json.NewDecoder(bufio.NewReader(f)).Decode(&entry); err != nil {
+		return nil, fmt.Errorf("failed to decode cache entry %s: %v", path, err)
+	}
+	return &entry, nil
+}
+
+// save writes an entry to a temporary file first, so concurrent readers never
+// see a partial entry. A failure only costs a later refetch, so it is ignored.
+func (c *Cache) save(key string, entry *Entry) {
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), key+".tmp*")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+// Walk calls fn with the path and contents of every cached entry.
+func (c *Cache) Walk(fn func(path string, entry *Entry) error) error {
+	err := filepath.WalkDir(c.Dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+		entry, err := readEntry(path)
+		if err != nil {
+			return err
+		}
+		return fn(path, entry)
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
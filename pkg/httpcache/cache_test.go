@@ -0,0 +1,266 @@
+package httpcache
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testServer serves every path with a fixed body, counting requests per path.
+// Paths under /etag/ carry an ETag and answer 304 to a matching
+// If-None-Match, with fresh rate limit headers.
+type testServer struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	hits      map[string]int
+	remaining int
+}
+
+func newTestServer(t *testing.T) *testServer {
+	s := &testServer{hits: make(map[string]int), remaining: 5000}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.hits[r.URL.Path]++
+		s.remaining--
+		remaining := s.remaining
+		s.mu.Unlock()
+
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		switch {
+		case r.URL.Path == "/missing":
+			http.NotFound(w, r)
+			return
+		case strings.HasPrefix(r.URL.Path, "/etag/"):
+			w.Header().Set("ETag", `"v1"`)
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+		io.WriteString(w, "body of "+r.URL.Path)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *testServer) hitsOf(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hits[path]
+}
+
+func get(t *testing.T, c *Cache, url, auth string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	resp, err := c.Client().Do(req)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", url, err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode == http.StatusOK && string(body) != "body of "+resp.Request.URL.Path {
+		t.Errorf("GET %s returned %q", url, body)
+	}
+	return resp
+}
+
+func newTestCache(t *testing.T, ttl time.Duration) *Cache {
+	c, err := New(t.TempDir(), ttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestCacheKinds(t *testing.T) {
+	s := newTestServer(t)
+	tests := []struct {
+		name string
+		path string
+		ttl  time.Duration
+		// requests the server sees for two GETs
+		wantHits        int
+		wantRevalidated int64
+	}{
+		{name: "immutable artifact", path: "/logs/job/1/finished.json", ttl: time.Hour, wantHits: 1},
+		{name: "step graph", path: "/logs/job/2/artifacts/ci-operator-step-graph.json", ttl: time.Hour, wantHits: 1},
+		{name: "revalidated", path: "/etag/repos/o/r/issues/1/comments", ttl: time.Hour, wantHits: 2, wantRevalidated: 1},
+		{name: "history within ttl", path: "/pr-history/", ttl: time.Hour, wantHits: 1},
+		{name: "history past ttl", path: "/job-history/gs/bucket/job", ttl: time.Nanosecond, wantHits: 2},
+		{name: "history without ttl", path: "/pr-history", wantHits: 2},
+		{name: "not cached", path: "/search/issues", ttl: time.Hour, wantHits: 2},
+		{name: "not found", path: "/missing", ttl: time.Hour, wantHits: 2},
+	}
+	for _, tt := range tests {
+		c := newTestCache(t, tt.ttl)
+		before := s.hitsOf(tt.path)
+		get(t, c, s.URL+tt.path, "")
+		second := get(t, c, s.URL+tt.path, "")
+		if hits := s.hitsOf(tt.path) - before; hits != tt.wantHits {
+			t.Errorf("%s: server saw %d requests, want %d", tt.name, hits, tt.wantHits)
+		}
+		if stats := c.Stats(); stats.Revalidated != tt.wantRevalidated {
+			t.Errorf("%s: %d revalidated, want %d", tt.name, stats.Revalidated, tt.wantRevalidated)
+		}
+		if tt.wantHits == 1 && second.Header.Get("X-Cache") != "hit" {
+			t.Errorf("%s: second response not marked as a cache hit", tt.name)
+		}
+	}
+}
+
+func TestCacheProwJob(t *testing.T) {
+	var mu sync.Mutex
+	hits := 0
+	body := `{"status":{"state":"pending"}}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		hits++
+		io.WriteString(w, body)
+	}))
+	defer server.Close()
+	c := newTestCache(t, time.Hour)
+	fetch := func() {
+		resp, err := c.Client().Get(server.URL + "/logs/job/1/prowjob.json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	// a running job's prowjob.json changes, a completed one's does not
+	fetch()
+	fetch()
+	mu.Lock()
+	body = `{"status":{"state":"success","completionTime":"2023-07-03T12:00:00Z"}}`
+	mu.Unlock()
+	fetch()
+	fetch()
+	mu.Lock()
+	defer mu.Unlock()
+	if hits != 3 {
+		t.Errorf("server saw %d requests, want 3", hits)
+	}
+}
+
+func TestCacheRevalidationUpdatesRateLimit(t *testing.T) {
+	s := newTestServer(t)
+	c := newTestCache(t, time.Hour)
+	first := get(t, c, s.URL+"/etag/rate_limited", "")
+	second := get(t, c, s.URL+"/etag/rate_limited", "")
+	if second.StatusCode != http.StatusOK {
+		t.Fatalf("revalidated response has status %d, want 200", second.StatusCode)
+	}
+	before, after := first.Header.Get("X-RateLimit-Remaining"), second.Header.Get("X-RateLimit-Remaining")
+	if before == after {
+		t.Errorf("revalidated response kept the stored X-RateLimit-Remaining %s", before)
+	}
+
+	// the stored entry now holds the headers of the 304
+	var stored string
+	err := c.Walk(func(path string, entry *Entry) error {
+		stored = entry.Header.Get("X-RateLimit-Remaining")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored != after {
+		t.Errorf("stored X-RateLimit-Remaining is %s, want %s", stored, after)
+	}
+}
+
+func TestCacheHitDropsRateLimit(t *testing.T) {
+	s := newTestServer(t)
+	c := newTestCache(t, time.Hour)
+	get(t, c, s.URL+"/logs/job/1/started.json", "")
+	if hit := get(t, c, s.URL+"/logs/job/1/started.json", ""); hit.Header.Get("X-RateLimit-Remaining") != "" {
+		t.Errorf("cache hit carries the stale X-RateLimit-Remaining %s", hit.Header.Get("X-RateLimit-Remaining"))
+	}
+}
+
+func TestCacheKeyedByAuthorization(t *testing.T) {
+	s := newTestServer(t)
+	c := newTestCache(t, time.Hour)
+	path := "/logs/job/3/finished.json"
+	get(t, c, s.URL+path, "token a")
+	get(t, c, s.URL+path, "token b")
+	get(t, c, s.URL+path, "token a")
+	if hits := s.hitsOf(path); hits != 2 {
+		t.Errorf("server saw %d requests, want one per token", hits)
+	}
+}
+
+func TestCacheSkipsNonGet(t *testing.T) {
+	s := newTestServer(t)
+	c := newTestCache(t, time.Hour)
+	for i := 0; i < 2; i++ {
+		resp, err := c.Client().Post(s.URL+"/logs/job/4/finished.json", "application/json", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if hits := s.hitsOf("/logs/job/4/finished.json"); hits != 2 {
+		t.Errorf("server saw %d POSTs, want 2", hits)
+	}
+}
+
+func TestCachePurgeExpired(t *testing.T) {
+	s := newTestServer(t)
+	c := newTestCache(t, time.Hour)
+	get(t, c, s.URL+"/logs/job/5/finished.json", "")
+	get(t, c, s.URL+"/pr-history/", "")
+
+	// with a shorter TTL the history page is expired, the artifact never is
+	c.TTL = time.Nanosecond
+	var kinds []Kind
+	err := c.Walk(func(path string, entry *Entry) error {
+		kinds = append(kinds, entry.Kind)
+		if c.Expired(entry) {
+			return os.Remove(path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk() failed: %v", err)
+	}
+	if len(kinds) != 2 {
+		t.Errorf("Walk() visited %v, want 2 entries", kinds)
+	}
+
+	left := 0
+	err = c.Walk(func(path string, entry *Entry) error {
+		left++
+		if entry.Kind != KindImmutable {
+			t.Errorf("purge left a %s entry", entry.Kind)
+		}
+		return nil
+	})
+	if err != nil || left != 1 {
+		t.Errorf("Walk() after purge visited %d entries (%v), want 1", left, err)
+	}
+}
+
+func TestWalkMissingDir(t *testing.T) {
+	c := &Cache{Dir: t.TempDir() + "/none"}
+	if err := c.Walk(func(string, *Entry) error { return nil }); err != nil {
+		t.Errorf("Walk() of a missing directory failed: %v", err)
+	}
+}
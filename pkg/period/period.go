@@ -0,0 +1,171 @@
+// Package period turns names such as 2023-Q3, 2023-08, last-30d or a sprint
+// from a calendar file into the date windows PRs are looked at for.
+package period
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// DateLayout is the layout of dates on the command line and in files:
+// MM-DD-YYYY.
+const DateLayout = "01-02-2006"
+
+// Period is a window of whole days. PRs closed from the start of Start to the
+// end of End belong to it.
+type Period struct {
+	Name  string
+	Start time.Time
+	End   time.Time
+}
+
+// Calendar is a list of named periods such as sprints, read from a file like:
+//
+//	sprints:
+//	- name: sprint-241
+//	  start: 08-07-2023
+//	  end: 08-25-2023
+type Calendar []Period
+
+// ParseDate parses a MM-DD-YYYY date.
+func ParseDate(date string) (time.Time, error) {
+	return time.Parse(DateLayout, date)
+}
+
+// Range returns the period from start to end, named after both dates.
+func Range(start, end time.Time) Period {
+	return Period{
+		Name:  start.Format("2006-01-02") + "_" + end.Format("2006-01-02"),
+		Start: start,
+		End:   end,
+	}
+}
+
+// LoadCalendar reads a calendar file.
+func LoadCalendar(path string) (Calendar, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read calendar: %v", err)
+	}
+	var file struct {
+		Sprints []struct {
+			Name  string `yaml:"name"`
+			Start string `yaml:"start"`
+			End   string `yaml:"end"`
+		} `yaml:"sprints"`
+	}
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse calendar %s: %v", path, err)
+	}
+
+	var calendar Calendar
+	for _, s := range file.Sprints {
+		if s.Name == "" {
+			return nil, fmt.Errorf("calendar %s: every sprint needs a name", path)
+		}
+		start, err := ParseDate(s.Start)
+		if err != nil {
+			return nil, fmt.Errorf("calendar %s: invalid start of %s: %v", path, s.Name, err)
+		}
+		end, err := ParseDate(s.End)
+		if err != nil {
+			return nil, fmt.Errorf("calendar %s: invalid end of %s: %v", path, s.Name, err)
+		}
+		if end.Before(start) {
+			return nil, fmt.Errorf("calendar %s: %s ends before it starts", path, s.Name)
+		}
+		calendar = append(calendar, Period{Name: s.Name, Start: start, End: end})
+	}
+	return calendar, nil
+}
+
+// Get returns the sprint called name.
+func (c Calendar) Get(name string) (Period, bool) {
+	for _, p := range c {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Period{}, false
+}
+
+var (
+	quarterPattern = regexp.MustCompile(`^(\d{4})-[Qq]([1-4])$`)
+	monthPattern   = regexp.MustCompile(`^(\d{4})-(\d{2})$`)
+	lastPattern    = regexp.MustCompile(`^last-(\d+)d$`)
+)
+
+// Parse returns the period named by spec, one of:
+//   - a quarter such as 2023-Q3
+//   - a month such as 2023-08
+//   - the last days up to and including today, such as last-30d
+//   - a sprint of calendar
+//   - two MM-DD-YYYY dates joined by "..", such as 07-01-2023..07-15-2023
+func Parse(spec string, now time.Time, calendar Calendar) (Period, error) {
+	if p, ok := calendar.Get(spec); ok {
+		return p, nil
+	}
+	if m := quarterPattern.FindStringSubmatch(spec); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		quarter, _ := strconv.Atoi(m[2])
+		start := time.Date(year, time.Month(3*quarter-2), 1, 0, 0, 0, 0, time.UTC)
+		return Period{Name: m[1] + "-Q" + m[2], Start: start, End: start.AddDate(0, 3, -1)}, nil
+	}
+	if m := monthPattern.FindStringSubmatch(spec); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		month, _ := strconv.Atoi(m[2])
+		if month < 1 || month > 12 {
+			return Period{}, fmt.Errorf("invalid month in period %q", spec)
+		}
+		start := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+		return Period{Name: spec, Start: start, End: start.AddDate(0, 1, -1)}, nil
+	}
+	if m := lastPattern.FindStringSubmatch(spec); m != nil {
+		days, _ := strconv.Atoi(m[1])
+		if days < 1 {
+			return Period{}, fmt.Errorf("period %q holds no days", spec)
+		}
+		now = now.UTC()
+		end := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		return Period{Name: spec, Start: end.AddDate(0, 0, 1-days), End: end}, nil
+	}
+	if from, to, ok := strings.Cut(spec, ".."); ok {
+		start, err := ParseDate(from)
+		if err != nil {
+			return Period{}, fmt.Errorf("invalid start of period %q: %v", spec, err)
+		}
+		end, err := ParseDate(to)
+		if err != nil {
+			return Period{}, fmt.Errorf("invalid end of period %q: %v", spec, err)
+		}
+		if end.Before(start) {
+			return Period{}, fmt.Errorf("period %q ends before it starts", spec)
+		}
+		return Range(start, end), nil
+	}
+	return Period{}, fmt.Errorf("unknown period %q: want a quarter (2023-Q3), a month (2023-08), last-<days>d, a sprint or <start>..<end>", spec)
+}
+
+// ParseAll parses every spec, refusing the same period twice.
+func ParseAll(specs []string, now time.Time, calendar Calendar) ([]Period, error) {
+	var periods []Period
+	seen := make(map[string]bool)
+	for _, spec := range specs {
+		p, err := Parse(spec, now, calendar)
+		if err != nil {
+			return nil, err
+		}
+		if seen[p.Name] {
+			return nil, fmt.Errorf("period %s is given more than once", p.Name)
+		}
+		seen[p.Name] = true
+		periods = append(periods, p)
+	}
+	return periods, nil
+}
@@ -0,0 +1,117 @@
+package period
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func day(year int, month time.Month, d int) time.Time {
+	return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestParse(t *testing.T) {
+	now := time.Date(2023, 8, 15, 18, 30, 0, 0, time.FixedZone("CEST", 2*3600))
+	calendar := Calendar{{Name: "sprint-241", Start: day(2023, 8, 7), End: day(2023, 8, 25)}}
+	tests := []struct {
+		spec    string
+		want    Period
+		wantErr bool
+	}{
+		{spec: "2023-Q3", want: Period{Name: "2023-Q3", Start: day(2023, 7, 1), End: day(2023, 9, 30)}},
+		{spec: "2024-q1", want: Period{Name: "2024-Q1", Start: day(2024, 1, 1), End: day(2024, 3, 31)}},
+		{spec: "2023-Q4", want: Period{Name: "2023-Q4", Start: day(2023, 10, 1), End: day(2023, 12, 31)}},
+		{spec: "2023-Q5", wantErr: true},
+		{spec: "2023-08", want: Period{Name: "2023-08", Start: day(2023, 8, 1), End: day(2023, 8, 31)}},
+		{spec: "2024-02", want: Period{Name: "2024-02", Start: day(2024, 2, 1), End: day(2024, 2, 29)}},
+		{spec: "2023-13", wantErr: true},
+		{spec: "2023-00", wantErr: true},
+		{spec: "last-30d", want: Period{Name: "last-30d", Start: day(2023, 7, 17), End: day(2023, 8, 15)}},
+		{spec: "last-1d", want: Period{Name: "last-1d", Start: day(2023, 8, 15), End: day(2023, 8, 15)}},
+		{spec: "last-0d", wantErr: true},
+		{spec: "sprint-241", want: calendar[0]},
+		{spec: "sprint-242", wantErr: true},
+		{spec: "07-01-2023..07-15-2023", want: Period{Name: "2023-07-01_2023-07-15", Start: day(2023, 7, 1), End: day(2023, 7, 15)}},
+		{spec: "07-15-2023..07-15-2023", want: Period{Name: "2023-07-15_2023-07-15", Start: day(2023, 7, 15), End: day(2023, 7, 15)}},
+		{spec: "07-15-2023..07-01-2023", wantErr: true},
+		{spec: "2023-07-01..07-15-2023", wantErr: true},
+		{spec: "07-01-2023..", wantErr: true},
+		{spec: "yesterday", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.spec, now, calendar)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Parse(%q) error = %v, want error %t", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestParseAll(t *testing.T) {
+	now := day(2023, 8, 15)
+	tests := []struct {
+		name      string
+		specs     []string
+		wantNames []string
+		wantErr   bool
+	}{
+		{name: "in order", specs: []string{"2023-Q2", "2023-07", "last-7d"}, wantNames: []string{"2023-Q2", "2023-07", "last-7d"}},
+		{name: "duplicate", specs: []string{"2023-07", "2023-07"}, wantErr: true},
+		// both spellings name the same quarter
+		{name: "duplicate quarter", specs: []string{"2023-Q3", "2023-q3"}, wantErr: true},
+		{name: "invalid", specs: []string{"2023-07", "2023-13"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		periods, err := ParseAll(tt.specs, now, nil)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: ParseAll() error = %v, want error %t", tt.name, err, tt.wantErr)
+			continue
+		}
+		if len(periods) != len(tt.wantNames) {
+			t.Errorf("%s: ParseAll() = %+v, want %v", tt.name, periods, tt.wantNames)
+			continue
+		}
+		for i, p := range periods {
+			if p.Name != tt.wantNames[i] {
+				t.Errorf("%s: period %d is %s, want %s", tt.name, i, p.Name, tt.wantNames[i])
+			}
+		}
+	}
+}
+
+func TestLoadCalendar(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    Calendar
+		wantErr bool
+	}{
+		{
+			name: "sprints",
+			data: "sprints:\n- name: sprint-241\n  start: 08-07-2023\n  end: 08-25-2023\n",
+			want: Calendar{{Name: "sprint-241", Start: day(2023, 8, 7), End: day(2023, 8, 25)}},
+		},
+		{name: "no name", data: "sprints:\n- start: 08-07-2023\n  end: 08-25-2023\n", wantErr: true},
+		{name: "bad date", data: "sprints:\n- name: s\n  start: 2023-08-07\n  end: 08-25-2023\n", wantErr: true},
+		{name: "reversed", data: "sprints:\n- name: s\n  start: 08-25-2023\n  end: 08-07-2023\n", wantErr: true},
+		{name: "unknown field", data: "sprint:\n- name: s\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "sprints.yaml")
+		if err := os.WriteFile(path, []byte(tt.data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := LoadCalendar(path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: LoadCalendar() error = %v, want error %t", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && (len(got) != 1 || got[0] != tt.want[0]) {
+			t.Errorf("%s: LoadCalendar() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
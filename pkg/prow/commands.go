@@ -0,0 +1,101 @@
+package prow
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Command is a Prow slash command such as /retest, /test e2e-aws-ovn or /hold
+// written in a PR comment.
+type Command struct {
+	Name      string
+	Args      []string
+	Author    string
+	CreatedAt time.Time
+}
+
+var commandPattern = regexp.MustCompile(`^/([a-zA-Z][a-zA-Z0-9-]*)(?:[ \t]+(.*))?$`)
+
+// ParseCommands returns the commands in a comment body. Like Prow, it only
+// looks at commands at the start of a line and ignores quoted lines and
+// fenced code blocks.
+func ParseCommands(body, author string, createdAt time.Time) []Command {
+	var commands []Command
+	inCodeBlock := false
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "```") {
+			inCodeBlock = !inCodeBlock
+			continue
+		}
+		if inCodeBlock || strings.HasPrefix(line, ">") {
+			continue
+		}
+
+		match := commandPattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		commands = append(commands, Command{
+			Name:      strings.ToLower(match[1]),
+			Args:      strings.Fields(match[2]),
+			Author:    author,
+			CreatedAt: createdAt,
+		})
+	}
+	return commands
+}
+
+// IsRetest reports whether the command asks Prow to run jobs again.
+func (c Command) IsRetest() bool {
+	switch c.Name {
+	case "retest", "retest-required", "test":
+		return true
+	}
+	return false
+}
+
+// MatchJobs returns the names of the jobs of runs a /test command triggers.
+// A /test argument names the test as configured in ci-operator, which Prow
+// prefixes with pull-ci-<org>-<repo>-<branch>- to form the job name, so it
+// matches the rest of the job name whole. /test all matches every job, and
+// other commands match none.
+func (c Command) MatchJobs(runs []JobRun) []string {
+	if c.Name != "test" {
+		return nil
+	}
+	var matched []string
+	seen := make(map[string]bool)
+	for _, run := range runs {
+		if seen[run.JobName] {
+			continue
+		}
+		shortName := ShortName(run.JobName, run.Refs)
+		for _, arg := range c.Args {
+			if arg == "all" || arg == shortName || arg == run.JobName {
+				matched = append(matched, run.JobName)
+				seen[run.JobName] = true
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// ShortName returns the name a presubmit job is triggered with, such as
+// e2e-aws-ovn for pull-ci-openshift-ovn-kubernetes-master-e2e-aws-ovn. Without
+// refs to find the pull-ci-<org>-<repo>-<branch>- prefix by, the job name is
+// returned as it is.
+func ShortName(jobName string, refs *Refs) string {
+	if refs == nil {
+		return jobName
+	}
+	return strings.TrimPrefix(jobName, "pull-ci-"+refs.Org+"-"+refs.Repo+"-"+refs.BaseRef+"-")
+}
+
+// IsBot reports whether a comment author is an automation account, such as
+// openshift-ci-robot, which issues retests on behalf of the retest plugin.
+func IsBot(author string) bool {
+	return strings.HasSuffix(author, "[bot]") || strings.HasSuffix(author, "-robot") || strings.HasSuffix(author, "-bot")
+}
@@ -0,0 +1,106 @@
+package prow
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseCommands(t *testing.T) {
+	created := time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+	body := "Flaky again.\n" +
+		"/retest\n" +
+		"  /test e2e-aws-ovn e2e-gcp-ovn  \n" +
+		"/retest-required quota issue\n" +
+		"> /hold\n" +
+		"```\n/override ci/prow/unit\n```\n" +
+		"not a /command mid-line\n" +
+		"/HOLD\n"
+	want := []Command{
+		{Name: "retest", Author: "someone", CreatedAt: created},
+		{Name: "test", Args: []string{"e2e-aws-ovn", "e2e-gcp-ovn"}, Author: "someone", CreatedAt: created},
+		{Name: "retest-required", Args: []string{"quota", "issue"}, Author: "someone", CreatedAt: created},
+		{Name: "hold", Author: "someone", CreatedAt: created},
+	}
+	got := ParseCommands(body, "someone", created)
+	for i := range got {
+		if len(got[i].Args) == 0 {
+			got[i].Args = nil
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseCommands() = %+v, want %+v", got, want)
+	}
+}
+
+func TestMatchJobs(t *testing.T) {
+	refs := testRefs(1700, "deadbeef")
+	release := &Refs{Org: "openshift", Repo: "ovn-kubernetes", BaseRef: "release-4.14"}
+	runs := []JobRun{
+		{JobName: "pull-ci-openshift-ovn-kubernetes-master-e2e-aws-ovn", Refs: refs},
+		{JobName: "pull-ci-openshift-ovn-kubernetes-master-e2e-aws-ovn-upgrade", Refs: refs},
+		{JobName: "pull-ci-openshift-ovn-kubernetes-master-unit", Refs: refs},
+		{JobName: "pull-ci-openshift-ovn-kubernetes-master-e2e-aws-ovn", Refs: refs},
+		{JobName: "pull-ci-openshift-ovn-kubernetes-release-4.14-upgrade", Refs: release},
+		{JobName: "pull-ci-openshift-ovn-kubernetes-release-4.14-okd-scos-e2e-aws-ovn", Refs: release},
+		// runs whose refs are unknown match their full job name only
+		{JobName: "pull-ci-openshift-ovn-kubernetes-master-images"},
+	}
+	tests := []struct {
+		command Command
+		want    []string
+	}{
+		{Command{Name: "test", Args: []string{"e2e-aws-ovn"}}, []string{runs[0].JobName}},
+		{Command{Name: "test", Args: []string{"unit", "e2e-aws-ovn-upgrade"}}, []string{runs[1].JobName, runs[2].JobName}},
+		// a test whose name ends another test's does not match it
+		{Command{Name: "test", Args: []string{"ovn"}}, nil},
+		{Command{Name: "test", Args: []string{"aws-ovn"}}, nil},
+		{Command{Name: "test", Args: []string{"upgrade"}}, []string{runs[4].JobName}},
+		// variant jobs are triggered by the variant and the test
+		{Command{Name: "test", Args: []string{"okd-scos-e2e-aws-ovn"}}, []string{runs[5].JobName}},
+		{Command{Name: "test", Args: []string{"images"}}, nil},
+		{Command{Name: "test", Args: []string{runs[6].JobName}}, []string{runs[6].JobName}},
+		{Command{Name: "test", Args: []string{"all"}}, []string{runs[0].JobName, runs[1].JobName, runs[2].JobName, runs[4].JobName, runs[5].JobName, runs[6].JobName}},
+		{Command{Name: "retest"}, nil},
+	}
+	for _, tt := range tests {
+		if got := tt.command.MatchJobs(runs); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("MatchJobs(%v) = %v, want %v", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestShortName(t *testing.T) {
+	refs := testRefs(1700, "deadbeef")
+	tests := []struct {
+		jobName string
+		refs    *Refs
+		want    string
+	}{
+		{"pull-ci-openshift-ovn-kubernetes-master-e2e-aws-ovn", refs, "e2e-aws-ovn"},
+		{"pull-ci-openshift-ovn-kubernetes-release-4.14-e2e-aws-ovn", &Refs{Org: "openshift", Repo: "ovn-kubernetes", BaseRef: "release-4.14"}, "e2e-aws-ovn"},
+		{"pull-ci-openshift-ovn-kubernetes-master-e2e-aws-ovn", nil, "pull-ci-openshift-ovn-kubernetes-master-e2e-aws-ovn"},
+		// a job of another repo keeps its prefix
+		{"pull-ci-openshift-cluster-network-operator-master-unit", refs, "pull-ci-openshift-cluster-network-operator-master-unit"},
+	}
+	for _, tt := range tests {
+		if got := ShortName(tt.jobName, tt.refs); got != tt.want {
+			t.Errorf("ShortName(%q) = %q, want %q", tt.jobName, got, tt.want)
+		}
+	}
+}
+
+func TestIsBot(t *testing.T) {
+	tests := map[string]bool{
+		"openshift-ci-robot":  true,
+		"openshift-ci[bot]":   true,
+		"openshift-merge-bot": true,
+		"robotnik":            false,
+		"someone":             false,
+	}
+	for author, want := range tests {
+		if got := IsBot(author); got != want {
+			t.Errorf("IsBot(%q) = %t, want %t", author, got, want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package prow
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+type Presubmit struct {
+	Name          string `yaml:"name"`
+	AlwaysRun     bool   `yaml:"always_run"`
+	Optional      bool   `yaml:"optional"`
+	SuccessCount  int
+	FailureCount  int
+	AbortedCount  int
+	PendingCount  int
+	ErrorCount    int
+	UnknownCount  int
+	PassRate      float64
+	TotalJobCount int
+}
+
+type Presubmits struct {
+	PresubmitJobs map[string][]Presubmit `yaml:"presubmits"`
+}
+
+// JobHistory holds the result counts of the runs found on job-history pages.
+type JobHistory struct {
+	SuccessCount          int
+	FailureCount          int
+	AbortedCount          int
+	PendingCount          int
+	ErrorCount            int
+	UnknownCount          int
+	UnexpectedStatusCount int
+}
+
+// Total returns the number of runs with a recognised result.
+func (h JobHistory) Total() int {
+	return h.SuccessCount + h.FailureCount + h.AbortedCount + h.PendingCount + h.ErrorCount + h.UnknownCount
+}
+
+// GetPresubmits fetches the presubmit job definitions of org/repo from the
+// job configuration of the Prow deployment.
+func (c *Client) GetPresubmits(ctx context.Context, org, repo string) (*Presubmits, error) {
+	if c.Profile.PresubmitsURL == "" {
+		return nil, fmt.Errorf("prow profile %s has no presubmits_url", c.Profile.Name)
+	}
+	url := expandPath(c.Profile.PresubmitsURL, org, repo, 0, "", "")
+	body, err := c.fetch(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+
+	var presubmits Presubmits
+
+	err = yaml.Unmarshal(data, &presubmits)
+	if err != nil {
+		return nil, err
+	}
+	return &presubmits, nil
+}
+
+// JobHistoryURL returns the job-history page of a presubmit job.
+func (c *Client) JobHistoryURL(jobName string) string {
+	return fmt.Sprintf("%s/job-history/gs/%s/%s?buildId=", c.Profile.DeckURL, c.Profile.Bucket, expandPath(c.Profile.JobHistoryPath, "", "", 0, jobName, ""))
+}
+
+// GetJobHistory counts the results of the runs on the job-history page at url
+// and on up to depth older pages.
+func (c *Client) GetJobHistory(ctx context.Context, url string, depth int) (JobHistory, error) {
+	var history JobHistory
+
+	err := c.processPage(ctx, url, &history, depth)
+	if err != nil {
+		return JobHistory{}, err
+	}
+
+	return history, nil
+}
+
+func (c *Client) processPage(ctx context.Context, url string, history *JobHistory, depth int) error {
+	// follow the "Older Runs" links until depth is used up
+	for ; depth >= 0 && url != ""; depth-- {
+		body, err := c.fetch(ctx, url)
+		if err != nil {
+			return err
+		}
+		runs, olderRunsURL, err := ParseJobHistory(body, c.Profile.DeckURL)
+		body.Close()
+		if err != nil {
+			return err
+		}
+
+		for _, run := range runs {
+			switch run.State {
+			case StateSuccess:
+				history.SuccessCount++
+			case StateFailure:
+				history.FailureCount++
+			case StateAborted:
+				history.AbortedCount++
+			case StatePending:
+				history.PendingCount++
+			case StateError:
+				history.ErrorCount++
+			case "unknown":
+				history.UnknownCount++
+			default:
+				history.UnexpectedStatusCount++
+			}
+		}
+		url = olderRunsURL
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package prow
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Profile describes a Prow deployment: where deck and the artifacts live and
+// how presubmit artifacts are laid out in the bucket. Path layouts use the
+// {org}, {repo}, {pr}, {job} and {build} placeholders.
+type Profile struct {
+	Name string `yaml:"name"`
+	// deck, e.g. https://prow.ci.openshift.org
+	DeckURL string `yaml:"deck_url"`
+	// gcsweb root that bucket paths are appended to
+	ArtifactsURL string `yaml:"artifacts_url"`
+	Bucket       string `yaml:"bucket"`
+	// directory of a presubmit run within the bucket
+	PRLogsPath string `yaml:"pr_logs_path"`
+	// directory deck lists the runs of a presubmit job from
+	JobHistoryPath string `yaml:"job_history_path"`
+	// raw URL of the presubmit job configuration of a repo
+	PresubmitsURL string `yaml:"presubmits_url"`
+	// org/repo or org entries this profile is used for
+	Repos []string `yaml:"repos"`
+}
+
+// Profiles are the known Prow deployments. The first one is the default.
+type Profiles []Profile
+
+// DefaultProfiles covers OpenShift CI, before and after its move to the
+// test-platform-results bucket, and upstream prow.k8s.io.
+var DefaultProfiles = Profiles{
+	{
+		Name:           "openshift",
+		DeckURL:        "https://prow.ci.openshift.org",
+		ArtifactsURL:   "https://gcsweb-ci.apps.ci.l2s4.p1.openshiftapps.com/gcs",
+		Bucket:         "test-platform-results",
+		PRLogsPath:     "pr-logs/pull/{org}_{repo}/{pr}/{job}/{build}",
+		JobHistoryPath: "pr-logs/directory/{job}",
+		PresubmitsURL:  "https://raw.githubusercontent.com/openshift/release/master/ci-operator/jobs/{org}/{repo}/{org}-{repo}-master-presubmits.yaml",
+		Repos:          []string{"openshift", "openshift-priv"},
+	},
+	{
+		Name:           "openshift-origin-ci-test",
+		DeckURL:        "https://prow.ci.openshift.org",
+		ArtifactsURL:   "https://gcsweb-ci.apps.ci.l2s4.p1.openshiftapps.com/gcs",
+		Bucket:         "origin-ci-test",
+		PRLogsPath:     "pr-logs/pull/{org}_{repo}/{pr}/{job}/{build}",
+		JobHistoryPath: "pr-logs/directory/{job}",
+		PresubmitsURL:  "https://raw.githubusercontent.com/openshift/release/master/ci-operator/jobs/{org}/{repo}/{org}-{repo}-master-presubmits.yaml",
+	},
+	{
+		Name:           "k8s",
+		DeckURL:        "https://prow.k8s.io",
+		ArtifactsURL:   "https://gcsweb.k8s.io/gcs",
+		Bucket:         "kubernetes-jenkins",
+		PRLogsPath:     "pr-logs/pull/{org}_{repo}/{pr}/{job}/{build}",
+		JobHistoryPath: "pr-logs/directory/{job}",
+		PresubmitsURL:  "https://raw.githubusercontent.com/kubernetes/test-infra/master/config/jobs/{org}/{repo}/{repo}-presubmits.yaml",
+		Repos:          []string{"kubernetes", "kubernetes-sigs"},
+	},
+}
+
+// LoadProfiles reads profiles from a YAML file with a top level "profiles"
+// list. Profiles with the name of a built-in one replace it.
+func LoadProfiles(path string) (Profiles, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Profiles []Profile `yaml:"profiles"`
+	}
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+
+	profiles := append(Profiles{}, DefaultProfiles...)
+	for _, p := range file.Profiles {
+		if p.Name == "" || p.DeckURL == "" || p.ArtifactsURL == "" || p.Bucket == "" || p.PRLogsPath == "" {
+			return nil, fmt.Errorf("profile %q in %s needs name, deck_url, artifacts_url, bucket and pr_logs_path", p.Name, path)
+		}
+		if p.JobHistoryPath == "" {
+			p.JobHistoryPath = "pr-logs/directory/{job}"
+		}
+		if i := profiles.index(p.Name); i >= 0 {
+			profiles[i] = p
+		} else {
+			profiles = append(profiles, p)
+		}
+	}
+	return profiles, nil
+}
+
+func (ps Profiles) index(name string) int {
+	for i, p := range ps {
+		if p.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Get returns the profile called name.
+func (ps Profiles) Get(name string) (Profile, error) {
+	if i := ps.index(name); i >= 0 {
+		return ps[i], nil
+	}
+	names := make([]string, 0, len(ps))
+	for _, p := range ps {
+		names = append(names, p.Name)
+	}
+	return Profile{}, fmt.Errorf("unknown Prow profile %q, known profiles: %s", name, strings.Join(names, ", "))
+}
+
+// ForRepo returns the profile listing org/repo, or else its org, falling back
+// to the first profile.
+func (ps Profiles) ForRepo(org, repo string) Profile {
+	for _, p := range ps {
+		for _, r := range p.Repos {
+			if r == org+"/"+repo {
+				return p
+			}
+		}
+	}
+	for _, p := range ps {
+		for _, r := range p.Repos {
+			if r == org {
+				return p
+			}
+		}
+	}
+	return ps[0]
+}
+
+func expandPath(layout, org, repo string, prNum int, jobName, buildID string) string {
+	return strings.NewReplacer(
+		"{org}", org,
+		"{repo}", repo,
+		"{pr}", strconv.Itoa(prNum),
+		"{job}", jobName,
+		"{build}", buildID,
+	).Replace(layout)
+}
@@ -0,0 +1,128 @@
+// Package prow reads pull request job history and job run timings from a Prow
+// deployment, such as OpenShift CI, and its GCS artifacts.
+package prow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Client reads job data from the Prow deployment described by its profile.
+type Client struct {
+	Profile    Profile
+	HTTPClient *http.Client
+}
+
+// NewClient returns a Client for the Prow deployment described by profile.
+func NewClient(profile Profile) *Client {
+	return &Client{Profile: profile, HTTPClient: http.DefaultClient}
+}
+
+// PRHistoryURL returns the pr-history page listing every job run for a PR.
+func (c *Client) PRHistoryURL(org, repo string, prNum int) string {
+	return fmt.Sprintf("%s/pr-history/?org=%s&repo=%s&pr=%d", c.Profile.DeckURL, org, repo, prNum)
+}
+
+// GetPRJobRuns returns every job run deck lists on the pr-history page of a PR.
+func (c *Client) GetPRJobRuns(ctx context.Context, org, repo string, prNum int) ([]JobRun, error) {
+	body, err := c.fetch(ctx, c.PRHistoryURL(org, repo, prNum))
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	return ParsePRHistory(body, c.Profile.DeckURL)
+}
+
+// RunArtifactsURL returns the gcsweb directory holding the artifacts of a run.
+// The bucket and path are taken from the run's Spyglass link when it has one,
+// so runs stored before a bucket move are still found.
+func (c *Client) RunArtifactsURL(org, repo string, prNum int, run JobRun) string {
+	const viewPrefix = "/view/gs/"
+	if i := strings.Index(run.URL, viewPrefix); i >= 0 {
+		return c.Profile.ArtifactsURL + "/" + run.URL[i+len(viewPrefix):]
+	}
+	return c.Profile.ArtifactsURL + "/" + c.Profile.Bucket + "/" + expandPath(c.Profile.PRLogsPath, org, repo, prNum, run.JobName, run.BuildID)
+}
+
+// GetProwJob fetches the prowjob.json of a job run.
+func (c *Client) GetProwJob(ctx context.Context, org, repo string, prNum int, run JobRun) (*ProwJob, error) {
+	body, err := c.fetch(ctx, c.RunArtifactsURL(org, repo, prNum, run)+"/prowjob.json")
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	return ParseProwJob(body)
+}
+
+// RunResult is what the started.json and finished.json artifacts of a job
+// run record.
+type RunResult struct {
+	Started  time.Time
+	Finished time.Time
+	// SUCCESS, FAILURE, ABORTED or ERROR
+	Result string
+	// the commit the run tested
+	Revision string
+}
+
+// startedJSON and finishedJSON are the fields of the started.json and
+// finished.json artifacts that are used.
+type startedJSON struct {
+	Timestamp *int64 `json:"timestamp"`
+}
+
+type finishedJSON struct {
+	Timestamp *int64 `json:"timestamp"`
+	Result    string `json:"result"`
+	Revision  string `json:"revision"`
+}
+
+// GetRunResult reads the started.json and finished.json artifacts of a job
+// run. In some cases the job could fail or abort and the files may not be
+// present, which is returned as an error wrapping ErrNotFound. When only
+// finished.json could not be read, the start time is returned along with the
+// error.
+func (c *Client) GetRunResult(ctx context.Context, org, repo string, prNum int, run JobRun) (RunResult, error) {
+	var result RunResult
+	artifactsURL := c.RunArtifactsURL(org, repo, prNum, run)
+
+	var started startedJSON
+	if err := c.getJSON(ctx, artifactsURL+"/started.json", &started); err != nil {
+		return result, err
+	}
+	if started.Timestamp == nil {
+		return result, fmt.Errorf("no timestamp in %s/started.json", artifactsURL)
+	}
+	result.Started = time.Unix(*started.Timestamp, 0).UTC()
+
+	var finished finishedJSON
+	if err := c.getJSON(ctx, artifactsURL+"/finished.json", &finished); err != nil {
+		return result, err
+	}
+	if finished.Timestamp == nil {
+		return result, fmt.Errorf("no timestamp in %s/finished.json", artifactsURL)
+	}
+	result.Finished = time.Unix(*finished.Timestamp, 0).UTC()
+	result.Result = finished.Result
+	result.Revision = finished.Revision
+	return result, nil
+}
+
+func (c *Client) getJSON(ctx context.Context, url string, v interface{}) error {
+	body, err := c.fetch(ctx, url)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	if err := json.NewDecoder(body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode %s: %v", url, err)
+	}
+	return nil
+}
@@ -0,0 +1,300 @@
+package prow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Job run states, as used by prowjob.json. Deck pages report the same states
+// in upper case.
+const (
+	StateSuccess = "success"
+	StateFailure = "failure"
+	StateAborted = "aborted"
+	StatePending = "pending"
+	StateError   = "error"
+)
+
+type Pull struct {
+	Number int    `json:"number"`
+	Author string `json:"author"`
+	SHA    string `json:"sha"`
+}
+
+// Refs are the repository revisions a job run tested.
+type Refs struct {
+	Org     string `json:"org"`
+	Repo    string `json:"repo"`
+	BaseRef string `json:"base_ref"`
+	BaseSHA string `json:"base_sha"`
+	Pulls   []Pull `json:"pulls,omitempty"`
+}
+
+// JobRun is a single run of a Prow job.
+type JobRun struct {
+	JobName    string
+	BuildID    string
+	State      string
+	StartTime  time.Time
+	FinishTime time.Time
+	// the Spyglass page of the run
+	URL  string
+	Refs *Refs
+}
+
+// PullSHA returns the PR commit the run tested, if its refs are known.
+func (r JobRun) PullSHA() string {
+	if r.Refs == nil || len(r.Refs.Pulls) == 0 {
+		return ""
+	}
+	return r.Refs.Pulls[0].SHA
+}
+
+// Finished reports whether the run has a finish time.
+func (r JobRun) Finished() bool {
+	return !r.FinishTime.IsZero()
+}
+
+// buildData is a run as embedded by deck in the allBuilds variable of its
+// job-history and pr-history pages.
+type buildData struct {
+	SpyglassLink string        `json:"SpyglassLink"`
+	ID           string        `json:"ID"`
+	Started      time.Time     `json:"Started"`
+	Duration     time.Duration `json:"Duration"`
+	Result       string        `json:"Result"`
+	Refs         *Refs         `json:"Refs"`
+}
+
+// prHistoryData is the allBuilds variable of a pr-history page.
+type prHistoryData struct {
+	Jobs []struct {
+		Name   string      `json:"Name"`
+		Builds []buildData `json:"Builds"`
+	} `json:"Jobs"`
+	Commits []struct {
+		Hash   string      `json:"Hash"`
+		Builds []buildData `json:"Builds"`
+	} `json:"Commits"`
+}
+
+func (b buildData) toJobRun(jobName, deckURL string) JobRun {
+	run := JobRun{
+		JobName:   jobName,
+		BuildID:   b.ID,
+		State:     strings.ToLower(b.Result),
+		StartTime: b.Started,
+		Refs:      b.Refs,
+	}
+	if b.SpyglassLink != "" {
+		run.URL = deckURL + b.SpyglassLink
+		if run.JobName == "" {
+			run.JobName, _ = ParseRunLink(b.SpyglassLink)
+		}
+	}
+	if run.State != StatePending && b.Duration > 0 {
+		run.FinishTime = b.Started.Add(b.Duration)
+	}
+	return run
+}
+
+// ParseRunLink returns the job name and build ID of a
+// .../<job-name>/<build-id> run link.
+func ParseRunLink(link string) (jobName, buildID string) {
+	segments := strings.Split(strings.TrimSuffix(link, "/"), "/")
+	if len(segments) < 2 {
+		return "", ""
+	}
+	return segments[len(segments)-2], segments[len(segments)-1]
+}
+
+// snowflakeEpoch is the epoch of the snowflake IDs Prow uses as build IDs, in
+// milliseconds since the Unix epoch.
+const snowflakeEpoch = 1288834974657
+
+// BuildIDTime returns the time encoded in a snowflake build ID, which is
+// within moments of the run's start.
+func BuildIDTime(buildID string) (time.Time, bool) {
+	id, err := strconv.ParseInt(buildID, 10, 64)
+	if err != nil || id <= 0 {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(id>>22 + snowflakeEpoch).UTC(), true
+}
+
+// allBuilds returns the JSON assigned to the allBuilds variable in a deck
+// page's scripts.
+func allBuilds(doc *goquery.Document) (json.RawMessage, error) {
+	const marker = "var allBuilds = "
+	var raw json.RawMessage
+	var err error
+	found := false
+	doc.Find("script").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		text := s.Text()
+		idx := strings.Index(text, marker)
+		if idx < 0 {
+			return true
+		}
+		found = true
+		// decode only the first value, whatever follows the assignment
+		err = json.NewDecoder(strings.NewReader(text[idx+len(marker):])).Decode(&raw)
+		return false
+	})
+	if !found {
+		return nil, fmt.Errorf("no allBuilds data found on page")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode allBuilds data: %v", err)
+	}
+	return raw, nil
+}
+
+// ParsePRHistory reads the job runs of a deck pr-history page. deckURL is
+// prepended to the relative Spyglass links.
+func ParsePRHistory(r io.Reader, deckURL string) ([]JobRun, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := allBuilds(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	var data prHistoryData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("failed to decode pr-history data: %v", err)
+	}
+
+	var runs []JobRun
+	seen := make(map[string]bool)
+	add := func(jobName string, b buildData) {
+		run := b.toJobRun(jobName, deckURL)
+		key := run.JobName + "/" + run.BuildID
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		runs = append(runs, run)
+	}
+	for _, job := range data.Jobs {
+		for _, b := range job.Builds {
+			add(job.Name, b)
+		}
+	}
+	// every run is listed under its job and again under its commit
+	for _, commit := range data.Commits {
+		for _, b := range commit.Builds {
+			add("", b)
+		}
+	}
+	return runs, nil
+}
+
+// ParseJobHistory reads the job runs of a deck job-history page and returns
+// them with the link to the page of older runs, if any.
+func ParseJobHistory(r io.Reader, deckURL string) ([]JobRun, string, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, "", err
+	}
+	raw, err := allBuilds(doc)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var builds []buildData
+	if err := json.Unmarshal(raw, &builds); err != nil {
+		return nil, "", fmt.Errorf("failed to decode job-history data: %v", err)
+	}
+	runs := make([]JobRun, 0, len(builds))
+	for _, b := range builds {
+		runs = append(runs, b.toJobRun("", deckURL))
+	}
+
+	olderRunsURL := ""
+	doc.Find("a").Each(func(i int, s *goquery.Selection) {
+		if strings.TrimSpace(s.Text()) == "<- Older Runs" {
+			if href, exists := s.Attr("href"); exists {
+				// the link is relative to deck
+				olderRunsURL = deckURL + href
+			}
+		}
+	})
+	return runs, olderRunsURL, nil
+}
+
+// ProwJob is the prowjob.json stored with the artifacts of a job run.
+type ProwJob struct {
+	Metadata struct {
+		Labels      map[string]string `json:"labels"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"metadata"`
+	Spec struct {
+		Type string `json:"type"`
+		Job  string `json:"job"`
+		Refs *Refs  `json:"refs"`
+	} `json:"spec"`
+	Status struct {
+		State          string     `json:"state"`
+		StartTime      time.Time  `json:"startTime"`
+		CompletionTime *time.Time `json:"completionTime"`
+		BuildID        string     `json:"build_id"`
+		URL            string     `json:"url"`
+	} `json:"status"`
+}
+
+// ParseProwJob reads a prowjob.json document.
+func ParseProwJob(r io.Reader) (*ProwJob, error) {
+	var job ProwJob
+	if err := json.NewDecoder(r).Decode(&job); err != nil {
+		return nil, fmt.Errorf("failed to decode prowjob.json: %v", err)
+	}
+	return &job, nil
+}
+
+// ErrNotFound is returned, wrapped, when a page or artifact does not exist.
+var ErrNotFound = errors.New("not found")
+
+func (c *Client) fetch(ctx context.Context, url string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to fetch %s: %w", url, ErrNotFound)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to fetch %s: %s", url, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+const (
+	cloudLabel          = "ci-operator.openshift.io/cloud"
+	clusterProfileLabel = "ci-operator.openshift.io/cloud-cluster-profile"
+)
+
+// ClusterProfile returns the ci-operator cluster profile the run used, or its
+// cloud when no profile is recorded. It is empty for jobs without a cluster.
+func (p *ProwJob) ClusterProfile() string {
+	if profile := p.Metadata.Labels[clusterProfileLabel]; profile != "" {
+		return profile
+	}
+	return p.Metadata.Labels[cloudLabel]
+}
@@ -0,0 +1,217 @@
+package prow
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const testDeckURL = "https://prow.ci.openshift.org"
+
+func openFixture(t *testing.T, name string) *os.File {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func runLink(pr, job, build string) string {
+	return testDeckURL + "/view/gs/test-platform-results/pr-logs/pull/openshift_ovn-kubernetes/" + pr + "/" + job + "/" + build
+}
+
+func testRefs(pr int, sha string) *Refs {
+	return &Refs{
+		Org:     "openshift",
+		Repo:    "ovn-kubernetes",
+		BaseRef: "master",
+		BaseSHA: "0a1b2c",
+		Pulls:   []Pull{{Number: pr, Author: "someone", SHA: sha}},
+	}
+}
+
+func TestParsePRHistory(t *testing.T) {
+	const (
+		awsJob  = "pull-ci-openshift-ovn-kubernetes-master-e2e-aws-ovn"
+		gcpJob  = "pull-ci-openshift-ovn-kubernetes-master-e2e-gcp-ovn"
+		unitJob = "pull-ci-openshift-ovn-kubernetes-master-unit"
+	)
+	at := func(s string) time.Time {
+		v, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	tests := []struct {
+		fixture  string
+		want     []JobRun
+		wantFail bool
+	}{
+		{
+			fixture: "pr-history.html",
+			want: []JobRun{
+				{
+					JobName:    awsJob,
+					BuildID:    "1676012345678901248",
+					State:      StateSuccess,
+					StartTime:  at("2023-07-03T10:00:00Z"),
+					FinishTime: at("2023-07-03T12:00:00Z"),
+					URL:        runLink("1700", awsJob, "1676012345678901248"),
+					Refs:       testRefs(1700, "deadbeef"),
+				},
+				{
+					JobName:    awsJob,
+					BuildID:    "1675900000000000000",
+					State:      StateFailure,
+					StartTime:  at("2023-07-02T08:00:00Z"),
+					FinishTime: at("2023-07-02T09:30:00Z"),
+					URL:        runLink("1700", awsJob, "1675900000000000000"),
+					Refs:       testRefs(1700, "cafef00d"),
+				},
+				{
+					JobName:   gcpJob,
+					BuildID:   "1676012345678901249",
+					State:     StatePending,
+					StartTime: at("2023-07-03T10:00:05Z"),
+					URL:       runLink("1700", gcpJob, "1676012345678901249"),
+					Refs:      testRefs(1700, "deadbeef"),
+				},
+				// only listed under its commit, its job name comes from the link
+				{
+					JobName:    unitJob,
+					BuildID:    "1676012345678901250",
+					State:      StateAborted,
+					StartTime:  at("2023-07-03T10:00:10Z"),
+					FinishTime: at("2023-07-03T10:15:10Z"),
+					URL:        runLink("1700", unitJob, "1676012345678901250"),
+					Refs:       testRefs(1700, "deadbeef"),
+				},
+			},
+		},
+		{fixture: "pr-history-empty.html"},
+		{fixture: "no-builds.html", wantFail: true},
+		{fixture: "truncated-builds.html", wantFail: true},
+		// a job-history page holds a list rather than jobs and commits
+		{fixture: "job-history.html", wantFail: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			got, err := ParsePRHistory(openFixture(t, tt.fixture), testDeckURL)
+			if tt.wantFail {
+				if err == nil {
+					t.Fatalf("ParsePRHistory() succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParsePRHistory() failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParsePRHistory() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseJobHistory(t *testing.T) {
+	const job = "pull-ci-openshift-ovn-kubernetes-master-e2e-aws-ovn"
+	tests := []struct {
+		fixture       string
+		wantRuns      []string
+		wantStates    []string
+		wantOlderRuns string
+		wantFail      bool
+	}{
+		{
+			fixture:       "job-history.html",
+			wantRuns:      []string{"1676100000000000000", "1676012345678901248", "1675000000000000000"},
+			wantStates:    []string{StateSuccess, StateFailure, StatePending},
+			wantOlderRuns: testDeckURL + "/job-history/gs/test-platform-results/pr-logs/directory/" + job + "?buildId=1675000000000000000",
+		},
+		{fixture: "job-history-empty.html"},
+		{fixture: "no-builds.html", wantFail: true},
+		{fixture: "truncated-builds.html", wantFail: true},
+		{fixture: "pr-history.html", wantFail: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			runs, olderRuns, err := ParseJobHistory(openFixture(t, tt.fixture), testDeckURL)
+			if tt.wantFail {
+				if err == nil {
+					t.Fatalf("ParseJobHistory() succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseJobHistory() failed: %v", err)
+			}
+			if len(runs) != len(tt.wantRuns) {
+				t.Fatalf("got %d runs, want %d", len(runs), len(tt.wantRuns))
+			}
+			for i, run := range runs {
+				if run.JobName != job || run.BuildID != tt.wantRuns[i] || run.State != tt.wantStates[i] {
+					t.Errorf("run %d is %s/%s %s, want %s/%s %s", i, run.JobName, run.BuildID, run.State, job, tt.wantRuns[i], tt.wantStates[i])
+				}
+				if run.Finished() == (run.State == StatePending) {
+					t.Errorf("run %d in state %s has finish time %s", i, run.State, run.FinishTime)
+				}
+			}
+			if olderRuns != tt.wantOlderRuns {
+				t.Errorf("older runs link is %q, want %q", olderRuns, tt.wantOlderRuns)
+			}
+		})
+	}
+}
+
+func TestParseProwJob(t *testing.T) {
+	job, err := ParseProwJob(openFixture(t, "prowjob.json"))
+	if err != nil {
+		t.Fatalf("ParseProwJob() failed: %v", err)
+	}
+	if got := job.ClusterProfile(); got != "aws-2" {
+		t.Errorf("ClusterProfile() = %q, want aws-2", got)
+	}
+	if job.Spec.Job != "pull-ci-openshift-ovn-kubernetes-master-e2e-aws-ovn" || job.Status.BuildID != "1676012345678901248" {
+		t.Errorf("ParseProwJob() read job %s build %s", job.Spec.Job, job.Status.BuildID)
+	}
+	if !reflect.DeepEqual(job.Spec.Refs, testRefs(1700, "deadbeef")) {
+		t.Errorf("ParseProwJob() read refs %+v", job.Spec.Refs)
+	}
+
+	delete(job.Metadata.Labels, clusterProfileLabel)
+	if got := job.ClusterProfile(); got != "aws" {
+		t.Errorf("ClusterProfile() without a profile label = %q, want the cloud aws", got)
+	}
+}
+
+func TestParseRunLink(t *testing.T) {
+	tests := []struct {
+		link, job, build string
+	}{
+		{"/view/gs/test-platform-results/pr-logs/pull/openshift_ovn-kubernetes/1700/e2e-aws/123", "e2e-aws", "123"},
+		{"https://prow.ci.openshift.org/view/gs/origin-ci-test/logs/e2e-aws/456/", "e2e-aws", "456"},
+		{"123", "", ""},
+	}
+	for _, tt := range tests {
+		job, build := ParseRunLink(tt.link)
+		if job != tt.job || build != tt.build {
+			t.Errorf("ParseRunLink(%q) = %q, %q, want %q, %q", tt.link, job, build, tt.job, tt.build)
+		}
+	}
+}
+
+func TestBuildIDTime(t *testing.T) {
+	got, ok := BuildIDTime("1676012345678901248")
+	want := time.Date(2023, 7, 3, 23, 37, 36, 670000000, time.UTC)
+	if !ok || !got.Equal(want) {
+		t.Errorf("BuildIDTime() = %s, %t, want %s", got, ok, want)
+	}
+	if _, ok := BuildIDTime("not-a-number"); ok {
+		t.Errorf("BuildIDTime() of an invalid ID succeeded")
+	}
+}
@@ -0,0 +1,124 @@
+package prow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Step is a ci-operator step, or a multi-stage test substep, of a job run.
+type Step struct {
+	Name       string     `json:"name"`
+	StartedAt  *time.Time `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+	Failed     *bool      `json:"failed,omitempty"`
+	// for substeps, the multi-stage test they belong to. Their Name is the
+	// step reference, such as ipi-install-install, without the test prefix.
+	Test string `json:"-"`
+}
+
+// Duration returns how long the step ran, or zero when it did not finish.
+func (s Step) Duration() time.Duration {
+	if s.StartedAt == nil || s.FinishedAt == nil {
+		return 0
+	}
+	return s.FinishedAt.Sub(*s.StartedAt)
+}
+
+type stepGraphNode struct {
+	Step
+	Substeps []Step `json:"substeps,omitempty"`
+}
+
+// provisionStepPatterns match the names of the multi-stage substeps that
+// create a job's cloud resources.
+var provisionStepPatterns = []string{
+	"ipi-install-install",
+	"upi-install",
+	"hypershift-install",
+	"packet-setup",
+}
+
+// GetSteps returns the steps and substeps recorded in the
+// ci-operator-step-graph.json artifact of a job run.
+func (c *Client) GetSteps(ctx context.Context, org, repo string, prNum int, run JobRun) ([]Step, error) {
+	body, err := c.fetch(ctx, c.RunArtifactsURL(org, repo, prNum, run)+"/artifacts/ci-operator-step-graph.json")
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	return ParseSteps(body)
+}
+
+// ParseSteps reads a ci-operator-step-graph.json document into its steps and
+// substeps.
+func ParseSteps(r io.Reader) ([]Step, error) {
+	var graph []stepGraphNode
+	if err := json.NewDecoder(r).Decode(&graph); err != nil {
+		return nil, fmt.Errorf("failed to decode ci-operator-step-graph.json: %v", err)
+	}
+
+	var steps []Step
+	for _, node := range graph {
+		steps = append(steps, node.Step)
+		for _, substep := range node.Substeps {
+			substep.Test = node.Name
+			substep.Name = strings.TrimPrefix(substep.Name, node.Name+"-")
+			steps = append(steps, substep)
+		}
+	}
+	return steps, nil
+}
+
+// ClusterWindow returns the time a run's cloud resources existed: from the
+// start of the first provisioning step to the end of the last deprovision
+// step, or to finished when the run has no deprovision step.
+func ClusterWindow(steps []Step, finished time.Time) (time.Time, time.Time, bool) {
+	start, ok := ProvisionStart(steps)
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	end := finished
+	deprovisioned := false
+	for _, step := range steps {
+		if step.FinishedAt == nil || !strings.Contains(step.Name, "deprovision") {
+			continue
+		}
+		if !deprovisioned || step.FinishedAt.After(end) {
+			end = *step.FinishedAt
+			deprovisioned = true
+		}
+	}
+	if !end.After(start) {
+		return time.Time{}, time.Time{}, false
+	}
+	return start, end, true
+}
+
+// ProvisionStart returns when the first step that creates cloud resources,
+// such as ipi-install-install, started.
+func ProvisionStart(steps []Step) (time.Time, bool) {
+	var start time.Time
+	for _, step := range steps {
+		if step.StartedAt == nil || !isProvisionStep(step.Name) {
+			continue
+		}
+		if start.IsZero() || step.StartedAt.Before(start) {
+			start = *step.StartedAt
+		}
+	}
+	return start, !start.IsZero()
+}
+
+func isProvisionStep(name string) bool {
+	for _, pattern := range provisionStepPatterns {
+		if strings.Contains(name, pattern) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,149 @@
+package prow
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSteps(t *testing.T) {
+	steps, err := ParseSteps(openFixture(t, "ci-operator-step-graph.json"))
+	if err != nil {
+		t.Fatalf("ParseSteps() failed: %v", err)
+	}
+	var names []string
+	for _, step := range steps {
+		names = append(names, step.Test+"/"+step.Name)
+	}
+	want := "/src /e2e-aws-ovn e2e-aws-ovn/ipi-conf e2e-aws-ovn/ipi-install-install e2e-aws-ovn/openshift-e2e-test e2e-aws-ovn/gather-must-gather e2e-aws-ovn/ipi-deprovision-deprovision"
+	if got := strings.Join(names, " "); got != want {
+		t.Errorf("ParseSteps() read steps %s, want %s", got, want)
+	}
+	if got := steps[3].Duration(); got != 40*time.Minute {
+		t.Errorf("install step took %s, want 40m", got)
+	}
+
+	start, end, ok := ClusterWindow(steps, at("12:01:30"))
+	if !ok || !start.Equal(at("10:05:00")) || !end.Equal(at("12:00:00")) {
+		t.Errorf("ClusterWindow() of the fixture = %s, %s, %t, want 10:05 to 12:00", start, end, ok)
+	}
+
+	if _, err := ParseSteps(strings.NewReader(`{"name": "src"}`)); err == nil {
+		t.Errorf("ParseSteps() of an object succeeded, want an error")
+	}
+}
+
+// at returns a time of the day the test runs happened.
+func at(clock string) time.Time {
+	t, err := time.Parse(time.RFC3339, "2023-07-03T"+clock+"Z")
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func step(name, started, finished string) Step {
+	s := Step{Name: name}
+	if started != "" {
+		t := at(started)
+		s.StartedAt = &t
+	}
+	if finished != "" {
+		t := at(finished)
+		s.FinishedAt = &t
+	}
+	return s
+}
+
+func TestClusterWindow(t *testing.T) {
+	finished := at("13:00:00")
+	tests := []struct {
+		name      string
+		steps     []Step
+		wantStart string
+		wantEnd   string
+		wantOK    bool
+	}{
+		{
+			name:  "no provision step",
+			steps: []Step{step("src", "10:00:00", "10:05:00"), step("unit", "10:05:00", "10:30:00")},
+		},
+		{
+			name:  "provision step that never started",
+			steps: []Step{step("ipi-install-install", "", ""), step("ipi-deprovision-deprovision", "11:00:00", "11:10:00")},
+		},
+		{
+			name:      "first provision step",
+			steps:     []Step{step("hypershift-install", "10:20:00", "10:40:00"), step("ipi-install-install", "10:10:00", "10:50:00"), step("ipi-deprovision-deprovision", "11:00:00", "11:10:00")},
+			wantStart: "10:10:00", wantEnd: "11:10:00", wantOK: true,
+		},
+		{
+			name:      "several deprovision steps",
+			steps:     []Step{step("ipi-install-install", "10:10:00", "10:50:00"), step("ipi-deprovision-deprovision", "11:00:00", "11:30:00"), step("hypershift-deprovision", "11:00:00", "11:10:00")},
+			wantStart: "10:10:00", wantEnd: "11:30:00", wantOK: true,
+		},
+		{
+			name:      "deprovision step that never finished",
+			steps:     []Step{step("ipi-install-install", "10:10:00", "10:50:00"), step("ipi-deprovision-deprovision", "11:00:00", "")},
+			wantStart: "10:10:00", wantEnd: "13:00:00", wantOK: true,
+		},
+		{
+			name:      "one of two deprovision steps finished",
+			steps:     []Step{step("ipi-install-install", "10:10:00", "10:50:00"), step("ipi-deprovision-deprovision", "11:00:00", ""), step("hypershift-deprovision", "11:00:00", "11:10:00")},
+			wantStart: "10:10:00", wantEnd: "11:10:00", wantOK: true,
+		},
+		{
+			name:      "no deprovision step",
+			steps:     []Step{step("upi-install-vsphere", "10:10:00", "10:50:00")},
+			wantStart: "10:10:00", wantEnd: "13:00:00", wantOK: true,
+		},
+		{
+			name:  "deprovisioned when provisioning started",
+			steps: []Step{step("ipi-install-install", "11:00:00", ""), step("ipi-deprovision-deprovision", "10:50:00", "11:00:00")},
+		},
+		{
+			name:  "deprovisioned before provisioning started",
+			steps: []Step{step("ipi-install-install", "11:00:00", ""), step("ipi-deprovision-deprovision", "10:50:00", "10:55:00")},
+		},
+		{
+			name:  "finished before provisioning started",
+			steps: []Step{step("packet-setup", "13:10:00", "")},
+		},
+	}
+	for _, tt := range tests {
+		start, end, ok := ClusterWindow(tt.steps, finished)
+		if ok != tt.wantOK {
+			t.Errorf("%s: ClusterWindow() ok = %t, want %t", tt.name, ok, tt.wantOK)
+			continue
+		}
+		if !ok {
+			if !start.IsZero() || !end.IsZero() {
+				t.Errorf("%s: ClusterWindow() = %s, %s without a window, want zero times", tt.name, start, end)
+			}
+			continue
+		}
+		if !start.Equal(at(tt.wantStart)) || !end.Equal(at(tt.wantEnd)) {
+			t.Errorf("%s: ClusterWindow() = %s to %s, want %s to %s", tt.name, start.Format("15:04:05"), end.Format("15:04:05"), tt.wantStart, tt.wantEnd)
+		}
+	}
+}
+
+func TestProvisionStart(t *testing.T) {
+	tests := []struct {
+		name   string
+		steps  []Step
+		want   string
+		wantOK bool
+	}{
+		{name: "none", steps: []Step{step("src", "10:00:00", "10:05:00")}},
+		{name: "empty", steps: nil},
+		{name: "ipi", steps: []Step{step("ipi-conf", "10:00:00", "10:01:00"), step("ipi-install-install", "10:02:00", "")}, want: "10:02:00", wantOK: true},
+		{name: "earliest of several", steps: []Step{step("packet-setup", "10:30:00", ""), step("upi-install-gcp", "10:20:00", "")}, want: "10:20:00", wantOK: true},
+	}
+	for _, tt := range tests {
+		got, ok := ProvisionStart(tt.steps)
+		if ok != tt.wantOK || (ok && !got.Equal(at(tt.want))) {
+			t.Errorf("%s: ProvisionStart() = %s, %t, want %s, %t", tt.name, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package report
+
+import (
+	"encoding/csv"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"cix/pkg/cost"
+	"cix/pkg/prow"
+)
+
+// csvWriter writes one row per PR, followed by one row per job run of the
+// PR. The kind column tells them apart.
+type csvWriter struct{}
+
+var prCostsHeader = []string{
+	"kind", "org", "repo", "pr", "job_name", "job_url", "platform", "status", "result", "start_time",
+	"hours", "cost", "retests", "bot_retests", "human_retests", "lifespan_days", "wasted_cost", "errors",
+}
+
+func (csvWriter) WritePRCosts(w io.Writer, r *cost.Report) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(prCostsHeader); err != nil {
+		return err
+	}
+	for _, prInfo := range r.PRs {
+		prNum := strconv.Itoa(prInfo.PRNum)
+		err := cw.Write([]string{
+			"pr", prInfo.Org, prInfo.Repo, prNum, "", prURL(prInfo), "", "", "", "",
+			formatFloat(prHours(prInfo)), formatFloat(prInfo.TotalCost),
+			strconv.Itoa(prInfo.PRRetestCount), strconv.Itoa(prInfo.BotRetests), strconv.Itoa(prInfo.HumanRetests),
+			formatFloat(prInfo.PRLifeSpan),
+			formatFloat(prInfo.Spend.Wasted()), strconv.Itoa(len(prInfo.Errors)),
+		})
+		if err != nil {
+			return err
+		}
+		for _, job := range prInfo.Jobs {
+			startTime := ""
+			if !job.StartTime.IsZero() {
+				startTime = job.StartTime.Format(time.RFC3339)
+			}
+			err := cw.Write([]string{
+				"job", prInfo.Org, prInfo.Repo, prNum, job.JobName, job.JobURL, string(job.Platform),
+				string(job.Status), job.Result, startTime,
+				formatFloat(job.Duration), formatFloat(job.Cost), "", "", "", "", "", "",
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func (csvWriter) WritePresubmits(w io.Writer, jobs []prow.Presubmit) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write([]string{
+		"name", "always_run", "optional", "success", "failure", "aborted", "pending", "error", "unknown", "pass_rate",
+	})
+	if err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		err := cw.Write([]string{
+			job.Name, strconv.FormatBool(job.AlwaysRun), strconv.FormatBool(job.Optional),
+			strconv.Itoa(job.SuccessCount), strconv.Itoa(job.FailureCount), strconv.Itoa(job.AbortedCount),
+			strconv.Itoa(job.PendingCount), strconv.Itoa(job.ErrorCount), strconv.Itoa(job.UnknownCount),
+			formatFloat(job.PassRate),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// formatFloat drops the float noise sums pick up, such as 2.7000000000000006.
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1e4)/1e4, 'f', -1, 64)
+}
@@ -0,0 +1,181 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"cix/pkg/cost"
+	"cix/pkg/prow"
+)
+
+// Writer writes PR cost and presubmit reports in one format.
+type Writer interface {
+	WritePRCosts(w io.Writer, r *cost.Report) error
+	WritePresubmits(w io.Writer, jobs []prow.Presubmit) error
+}
+
+type format struct {
+	// extension of the files written next to the JSON report
+	ext    string
+	writer Writer
+}
+
+var formats = map[string]format{
+	"json":     {".json", jsonWriter{}},
+	"csv":      {".csv", csvWriter{}},
+	"markdown": {".md", markdownWriter{}},
+	"html":     {".html", htmlWriter{}},
+}
+
+// formatAliases are other names accepted for formats.
+var formatAliases = map[string]string{"md": "markdown"}
+
+// Formats returns the names of the report formats, sorted.
+func Formats() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Output is a report format and the file it is written to, "-" for stdout.
+type Output struct {
+	Format string
+	Path   string
+}
+
+// ParseOutputs reads format specs such as "csv", "markdown=-" or
+// "html=report.html". Outputs without a path are written next to the JSON
+// report at base, with the format's extension. The JSON report is always
+// written, at base unless a spec gives it another path.
+func ParseOutputs(specs []string, base string) ([]Output, error) {
+	var outputs []Output
+	seen := make(map[string]bool)
+	for _, spec := range specs {
+		name, path, _ := strings.Cut(spec, "=")
+		if alias, ok := formatAliases[name]; ok {
+			name = alias
+		}
+		f, ok := formats[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown report format %q, want one of %s", name, strings.Join(Formats(), ", "))
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("report format %s is given more than once", name)
+		}
+		seen[name] = true
+		if path == "" {
+			path = strings.TrimSuffix(base, filepath.Ext(base)) + f.ext
+		}
+		outputs = append(outputs, Output{Format: name, Path: path})
+	}
+	if !seen["json"] {
+		outputs = append([]Output{{Format: "json", Path: base}}, outputs...)
+	}
+	return outputs, nil
+}
+
+// JSONPath returns the path of the JSON output.
+func JSONPath(outputs []Output) string {
+	for _, o := range outputs {
+		if o.Format == "json" {
+			return o.Path
+		}
+	}
+	return ""
+}
+
+// WritePRCosts writes a PR cost report to every output.
+func WritePRCosts(outputs []Output, r *cost.Report) error {
+	for _, o := range outputs {
+		writer := formats[o.Format].writer
+		if err := o.write(func(w io.Writer) error { return writer.WritePRCosts(w, r) }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WritePresubmits writes the presubmit results to every output.
+func WritePresubmits(outputs []Output, jobs []prow.Presubmit) error {
+	for _, o := range outputs {
+		writer := formats[o.Format].writer
+		if err := o.write(func(w io.Writer) error { return writer.WritePresubmits(w, jobs) }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o Output) write(write func(w io.Writer) error) error {
+	if o.Path == "-" {
+		return write(os.Stdout)
+	}
+	file, err := os.Create(o.Path)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %v", err)
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write %s report to %s: %v", o.Format, o.Path, err)
+	}
+	return file.Close()
+}
+
+type jsonWriter struct{}
+
+func (jsonWriter) WritePRCosts(w io.Writer, r *cost.Report) error {
+	return writeJSON(w, r)
+}
+
+func (jsonWriter) WritePresubmits(w io.Writer, jobs []prow.Presubmit) error {
+	return writeJSON(w, jobs)
+}
+
+// WriteJSON marshals v into the file at path, "-" for stdout.
+func WriteJSON(path string, v interface{}) error {
+	return Output{Format: "json", Path: path}.write(func(w io.Writer) error { return writeJSON(w, v) })
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	jsonData, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal to JSON: %v", err)
+	}
+	_, err = w.Write(jsonData)
+	return err
+}
+
+// prURL returns the page of a PR. Reports written before the page was
+// recorded link to github.com.
+func prURL(prInfo cost.PRInfo) string {
+	if prInfo.URL != "" {
+		return prInfo.URL
+	}
+	return fmt.Sprintf("https://github.com/%s/%s/pull/%d", prInfo.Org, prInfo.Repo, prInfo.PRNum)
+}
+
+// prHours returns the cloud hours of a PR on every platform.
+func prHours(prInfo cost.PRInfo) float64 {
+	hours := 0.0
+	for _, h := range prInfo.PlatformHours {
+		hours += h
+	}
+	return hours
+}
+
+func sortedRepos(byRepo map[string]cost.Spend) []string {
+	repos := make([]string, 0, len(byRepo))
+	for repo := range byRepo {
+		repos = append(repos, repo)
+	}
+	sort.Strings(repos)
+	return repos
+}
@@ -0,0 +1,129 @@
+package report
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"cix/pkg/cost"
+	"cix/pkg/prow"
+)
+
+// testReport is a report of two PRs whose job names, step names and errors
+// hold markup and table separators.
+func testReport() *cost.Report {
+	return &cost.Report{
+		GeneratedAt: time.Date(2023, 7, 5, 9, 0, 0, 0, time.UTC),
+		RateCard:    &cost.RateCard{Version: "2023-07"},
+		PRs: []cost.PRInfo{
+			{
+				Org:           "openshift",
+				Repo:          "ovn-kubernetes",
+				PRNum:         1700,
+				URL:           "https://github.example.com/openshift/ovn-kubernetes/pull/1700",
+				PRRetestCount: 2,
+				PlatformHours: map[cost.Platform]float64{cost.AWS: 1.5},
+				TotalCost:     3,
+				Spend:         cost.Spend{Successful: 2, Retested: 1},
+				Jobs: []cost.JobInfo{{
+					JobURL:   "https://prow.example.com/view/1",
+					JobName:  `e2e-<script>alert("job")</script>`,
+					Platform: cost.AWS,
+					Result:   "SUCCESS",
+					Duration: 1.5,
+					Cost:     3,
+					Steps:    []cost.StepInfo{{Name: "ipi|install", Duration: 0.5, Cost: 1}},
+				}},
+			},
+			{
+				Org:    "openshift",
+				Repo:   "cluster-network-operator",
+				PRNum:  9,
+				Errors: []string{`failed to get comments: <b>"rate" & limited</b>`},
+			},
+		},
+	}
+}
+
+func TestHTMLPRCosts(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (htmlWriter{}).WritePRCosts(&buf, testReport()); err != nil {
+		t.Fatalf("WritePRCosts() failed: %v", err)
+	}
+	out := buf.String()
+
+	// nothing is fetched when the file is opened
+	for _, external := range []string{"<link", "<script", "src=", "@import", "url("} {
+		if strings.Contains(out, external) {
+			t.Errorf("HTML report contains %s", external)
+		}
+	}
+	for _, m := range regexp.MustCompile(`<(\w+)[^>]*\shref=`).FindAllStringSubmatch(out, -1) {
+		if m[1] != "a" {
+			t.Errorf("HTML report has an href on a <%s>", m[1])
+		}
+	}
+
+	for _, want := range []string{
+		`<a href="https://github.example.com/openshift/ovn-kubernetes/pull/1700">openshift/ovn-kubernetes#1700</a>`,
+		`<a href="https://github.com/openshift/cluster-network-operator/pull/9">`,
+		`e2e-&lt;script&gt;alert(&#34;job&#34;)&lt;/script&gt;`,
+		`title="failed to get comments: &lt;b&gt;&#34;rate&#34; &amp; limited&lt;/b&gt;&#10;"`,
+		`<td class="name">ipi|install</td>`,
+		"priced with rate card 2023-07",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("HTML report does not contain %s", want)
+		}
+	}
+	if strings.Contains(out, "<b>") {
+		t.Errorf("HTML report contains the markup of an error unescaped")
+	}
+}
+
+func TestMarkdownPRCosts(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (markdownWriter{}).WritePRCosts(&buf, testReport()); err != nil {
+		t.Fatalf("WritePRCosts() failed: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"| [openshift/ovn-kubernetes#1700](https://github.example.com/openshift/ovn-kubernetes/pull/1700) | $3.00 | 1.50 | 1 | 2 | 0.0 | $1.00 | 0 |\n",
+		"| [openshift/cluster-network-operator#9](https://github.com/openshift/cluster-network-operator/pull/9) | $0.00 | 0.00 | 0 | 0 | 0.0 | $0.00 | 1 |\n",
+		"| ipi\\|install | 1 | 0.50 | $1.00 |\n",
+		"Generated 2023-07-05 09:00 UTC, priced with rate card 2023-07.",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Markdown report does not contain %q", want)
+		}
+	}
+
+	// every table row has as many cells as its header
+	cells := 0
+	for _, line := range strings.Split(out, "\n") {
+		if !strings.HasPrefix(line, "|") {
+			cells = 0
+			continue
+		}
+		n := strings.Count(strings.ReplaceAll(line, `\|`, ""), "|")
+		if cells == 0 {
+			cells = n
+		} else if n != cells {
+			t.Errorf("row %q has %d separators, want %d", line, n, cells)
+		}
+	}
+}
+
+func TestMarkdownPresubmits(t *testing.T) {
+	var buf bytes.Buffer
+	jobs := []prow.Presubmit{{Name: "e2e|<aws>", AlwaysRun: true, SuccessCount: 3, FailureCount: 1, PassRate: 0.75}}
+	if err := (markdownWriter{}).WritePresubmits(&buf, jobs); err != nil {
+		t.Fatalf("WritePresubmits() failed: %v", err)
+	}
+	want := "| e2e\\|&lt;aws&gt; | ✓ |  | 3 | 1 | 0 | 0 | 0 | 0 | 75% |\n"
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("WritePresubmits() wrote %q, want a row %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,130 @@
+package report
+
+import (
+	"html/template"
+	"io"
+
+	"cix/pkg/cost"
+	"cix/pkg/prow"
+)
+
+// htmlWriter writes a single HTML file with its styles inline, so it opens
+// anywhere without fetching anything.
+type htmlWriter struct{}
+
+type htmlPRCosts struct {
+	Report    *cost.Report
+	TotalCost float64
+	// cost of the most expensive PR, the full width of the bars
+	MaxCost float64
+	Spend   map[string]cost.Spend
+	Repos   []string
+	Steps   []cost.StepTotal
+}
+
+func (htmlWriter) WritePRCosts(w io.Writer, r *cost.Report) error {
+	data := htmlPRCosts{
+		Report: r,
+		Spend:  cost.SpendByRepo(r.PRs),
+		Steps:  cost.StepTotals(r.PRs),
+	}
+	data.Repos = sortedRepos(data.Spend)
+	for _, prInfo := range r.PRs {
+		data.TotalCost += prInfo.TotalCost
+		if prInfo.TotalCost > data.MaxCost {
+			data.MaxCost = prInfo.TotalCost
+		}
+	}
+	return htmlTemplates.ExecuteTemplate(w, "prcosts", data)
+}
+
+func (htmlWriter) WritePresubmits(w io.Writer, jobs []prow.Presubmit) error {
+	return htmlTemplates.ExecuteTemplate(w, "presubmits", jobs)
+}
+
+var htmlTemplates = template.Must(template.New("report").Funcs(template.FuncMap{
+	"dollars": dollars,
+	"hours":   prHours,
+	"prURL":   prURL,
+	"percent": func(v, max float64) float64 {
+		if max <= 0 {
+			return 0
+		}
+		return v / max * 100
+	},
+}).Parse(`
+{{define "head"}}<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #1f2328; }
+h1, h2 { font-weight: 600; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #d0d7de; padding: 4px 10px; text-align: right; vertical-align: top; }
+th { background: #f6f8fa; }
+td.name, th.name { text-align: left; }
+.bar { background: #54aeff; height: 10px; min-width: 1px; }
+.warn { background: #fff8c5; border: 1px solid #d4a72c; padding: 8px 12px; margin-bottom: 1em; }
+details summary { cursor: pointer; }
+details table { margin: 6px 0 0 0; font-size: 90%; }
+.muted { color: #656d76; }
+</style>
+</head>
+<body>
+{{end}}
+
+{{define "prcosts"}}{{template "head" "PR costs"}}
+<h1>PR costs</h1>
+<p class="muted">{{len .Report.PRs}} PRs, {{dollars .TotalCost}} in total.
+{{- if not .Report.GeneratedAt.IsZero}} Generated {{.Report.GeneratedAt.Format "2006-01-02 15:04 MST"}}{{end}}
+{{- with .Report.RateCard}}{{if .Version}}, priced with rate card {{.Version}}{{end}}{{end}}.</p>
+{{if .Report.Partial}}<div class="warn"><strong>Partial report:</strong> {{.Report.Interrupted}}, {{len .Report.Unprocessed}} PRs were not processed.</div>{{end}}
+
+<h2>Spend per repo</h2>
+<table>
+<tr><th class="name">Repo</th><th>Total</th><th>Wasted</th><th>Failed</th><th>Retested</th><th>Superseded</th><th>Successful</th><th>Other</th></tr>
+{{range .Repos}}{{$sp := index $.Spend .}}<tr><td class="name">{{.}}</td><td>{{dollars $sp.Total}}</td><td>{{dollars $sp.Wasted}}</td><td>{{dollars $sp.Failed}}</td><td>{{dollars $sp.Retested}}</td><td>{{dollars $sp.Superseded}}</td><td>{{dollars $sp.Successful}}</td><td>{{dollars $sp.Other}}</td></tr>
+{{end}}</table>
+
+<h2>Pull requests</h2>
+<table>
+<tr><th class="name">PR</th><th>Cost</th><th class="name" style="width: 200px"></th><th>Hours</th><th>Retests</th><th>Lifespan (days)</th><th>Wasted</th><th class="name">Job runs</th></tr>
+{{range .Report.PRs}}<tr>
+<td class="name"><a href="{{prURL .}}">{{.Key}}</a>{{if .Failed}} <span title="{{range .Errors}}{{.}}&#10;{{end}}">&#9888;</span>{{end}}</td>
+<td>{{dollars .TotalCost}}</td>
+<td class="name"><div class="bar" style="width: {{printf "%.1f" (percent .TotalCost $.MaxCost)}}%"></div></td>
+<td>{{printf "%.2f" (hours .)}}</td>
+<td>{{.PRRetestCount}}</td>
+<td>{{printf "%.1f" .PRLifeSpan}}</td>
+<td>{{dollars .Spend.Wasted}}</td>
+<td class="name"><details><summary>{{len .Jobs}} runs</summary>
+<table>
+<tr><th class="name">Job</th><th class="name">Platform</th><th class="name">Result</th><th>Hours</th><th>Cost</th></tr>
+{{range .Jobs}}{{if .JobURL}}<tr><td class="name"><a href="{{.JobURL}}">{{if .JobName}}{{.JobName}}{{else}}{{.JobURL}}{{end}}</a></td><td class="name">{{.Platform}}</td><td class="name">{{if .Result}}{{.Result}}{{else}}{{.Status}}{{end}}</td><td>{{printf "%.2f" .Duration}}</td><td>{{dollars .Cost}}</td></tr>
+{{end}}{{end}}</table>
+</details></td>
+</tr>
+{{end}}</table>
+{{if .Steps}}
+<h2>Step costs</h2>
+<table>
+<tr><th class="name">Step</th><th>Runs</th><th>Hours</th><th>Cost</th></tr>
+{{range .Steps}}<tr><td class="name">{{.Name}}</td><td>{{.Runs}}</td><td>{{printf "%.2f" .Hours}}</td><td>{{dollars .Cost}}</td></tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+{{end}}
+
+{{define "presubmits"}}{{template "head" "Presubmit jobs"}}
+<h1>Presubmit jobs</h1>
+<table>
+<tr><th class="name">Job</th><th>Always run</th><th>Optional</th><th>Success</th><th>Failure</th><th>Aborted</th><th>Pending</th><th>Error</th><th>Unknown</th><th>Pass rate</th><th class="name" style="width: 200px"></th></tr>
+{{range .}}<tr><td class="name">{{.Name}}</td><td>{{if .AlwaysRun}}&#10003;{{end}}</td><td>{{if .Optional}}&#10003;{{end}}</td><td>{{.SuccessCount}}</td><td>{{.FailureCount}}</td><td>{{.AbortedCount}}</td><td>{{.PendingCount}}</td><td>{{.ErrorCount}}</td><td>{{.UnknownCount}}</td><td>{{printf "%.0f" (percent .PassRate 1)}}%</td><td class="name"><div class="bar" style="width: {{printf "%.1f" (percent .PassRate 1)}}%"></div></td></tr>
+{{end}}</table>
+</body>
+</html>
+{{end}}
+`))
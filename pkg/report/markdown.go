@@ -0,0 +1,109 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"cix/pkg/cost"
+	"cix/pkg/prow"
+)
+
+// markdownWriter writes GitHub-flavored Markdown tables that can be pasted
+// into issues.
+type markdownWriter struct{}
+
+func (markdownWriter) WritePRCosts(w io.Writer, r *cost.Report) error {
+	mw := &mdWriter{w: w}
+	mw.printf("## PR costs\n\n")
+	if !r.GeneratedAt.IsZero() {
+		mw.printf("Generated %s", r.GeneratedAt.Format("2006-01-02 15:04 MST"))
+		if r.RateCard != nil && r.RateCard.Version != "" {
+			mw.printf(", priced with rate card %s", r.RateCard.Version)
+		}
+		mw.printf(".\n\n")
+	}
+	if r.Partial {
+		mw.printf("> **Partial report:** %s, %d PRs were not processed.\n\n", r.Interrupted, len(r.Unprocessed))
+	}
+
+	mw.row("PR", "Cost", "Hours", "Runs", "Retests", "Lifespan (days)", "Wasted", "Errors")
+	mw.row("---", "---:", "---:", "---:", "---:", "---:", "---:", "---:")
+	for _, prInfo := range r.PRs {
+		mw.row(
+			fmt.Sprintf("[%s](%s)", prInfo.Key(), prURL(prInfo)),
+			dollars(prInfo.TotalCost),
+			fmt.Sprintf("%.2f", prHours(prInfo)),
+			fmt.Sprint(len(prInfo.Jobs)),
+			fmt.Sprint(prInfo.PRRetestCount),
+			fmt.Sprintf("%.1f", prInfo.PRLifeSpan),
+			dollars(prInfo.Spend.Wasted()),
+			fmt.Sprint(len(prInfo.Errors)),
+		)
+	}
+
+	byRepo := cost.SpendByRepo(r.PRs)
+	mw.printf("\n### Spend per repo\n\n")
+	mw.row("Repo", "Total", "Wasted", "Failed", "Retested", "Superseded", "Successful", "Other")
+	mw.row("---", "---:", "---:", "---:", "---:", "---:", "---:", "---:")
+	for _, repo := range sortedRepos(byRepo) {
+		sp := byRepo[repo]
+		mw.row(repo, dollars(sp.Total()), dollars(sp.Wasted()), dollars(sp.Failed), dollars(sp.Retested),
+			dollars(sp.Superseded), dollars(sp.Successful), dollars(sp.Other))
+	}
+
+	if totals := cost.StepTotals(r.PRs); len(totals) > 0 {
+		mw.printf("\n### Step costs\n\n")
+		mw.row("Step", "Runs", "Hours", "Cost")
+		mw.row("---", "---:", "---:", "---:")
+		for _, total := range totals {
+			mw.row(total.Name, fmt.Sprint(total.Runs), fmt.Sprintf("%.2f", total.Hours), dollars(total.Cost))
+		}
+	}
+	return mw.err
+}
+
+func (markdownWriter) WritePresubmits(w io.Writer, jobs []prow.Presubmit) error {
+	mw := &mdWriter{w: w}
+	mw.printf("## Presubmit jobs\n\n")
+	mw.row("Job", "Always run", "Optional", "Success", "Failure", "Aborted", "Pending", "Error", "Unknown", "Pass rate")
+	mw.row("---", ":---:", ":---:", "---:", "---:", "---:", "---:", "---:", "---:", "---:")
+	for _, job := range jobs {
+		mw.row(job.Name, checkmark(job.AlwaysRun), checkmark(job.Optional),
+			fmt.Sprint(job.SuccessCount), fmt.Sprint(job.FailureCount), fmt.Sprint(job.AbortedCount),
+			fmt.Sprint(job.PendingCount), fmt.Sprint(job.ErrorCount), fmt.Sprint(job.UnknownCount),
+			fmt.Sprintf("%.0f%%", job.PassRate*100))
+	}
+	return mw.err
+}
+
+// mdWriter keeps the first write error so tables can be written without
+// checking every row.
+type mdWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (m *mdWriter) printf(format string, args ...interface{}) {
+	if m.err == nil {
+		_, m.err = fmt.Fprintf(m.w, format, args...)
+	}
+}
+
+func (m *mdWriter) row(cells ...string) {
+	for i, cell := range cells {
+		cells[i] = mdEscaper.Replace(cell)
+	}
+	m.printf("| %s |\n", strings.Join(cells, " | "))
+}
+
+// mdEscaper keeps cells from breaking out of their table or being read as
+// HTML.
+var mdEscaper = strings.NewReplacer("|", `\|`, "<", "&lt;", ">", "&gt;")
+
+func checkmark(b bool) string {
+	if b {
+		return "✓"
+	}
+	return ""
+}
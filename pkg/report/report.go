@@ -0,0 +1,233 @@
+// Package report writes PR cost and presubmit results to files and terminals.
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"cix/pkg/cost"
+	"cix/pkg/prow"
+	"cix/pkg/transport"
+)
+
+// PrintPRCosts writes the per-platform cloud usage of every PR.
+func PrintPRCosts(w io.Writer, prInfos []cost.PRInfo) {
+	fmt.Fprintln(w, "PR Costs (sorted from most expensive to least):")
+	for _, prInfo := range prInfos {
+		fmt.Fprintf(w, `
+	TOTAL PR COST:  $%.2f
+	TOTAL CLOUD USAGE FOR PR %s/%s/%d
+		COMMENTS
+			ISSUE: %d, REVIEW: %d, REVIEW COMMENT: %d
+`,
+			prInfo.TotalCost, prInfo.Org, prInfo.Repo, prInfo.PRNum,
+			prInfo.CommentCounts["issue"], prInfo.CommentCounts["review"], prInfo.CommentCounts["review_comment"])
+		for _, platform := range prInfo.Platforms() {
+			fmt.Fprintf(w, `		%s
+			HOURS: %.2f
+			COSTS: $%.2f
+`, strings.ToUpper(string(platform)), prInfo.PlatformHours[platform], prInfo.PlatformCosts[platform])
+		}
+		c := prInfo.Coverage
+		fmt.Fprintf(w, "\t\tCOVERAGE: %d runs, %d complete, %d running, %d aborted, %d fetch errors, %d without cluster, %d estimated\n",
+			c.Runs, c.Complete, c.Running, c.Aborted, c.FetchErrors, c.NoCluster, c.Estimated)
+		sp := prInfo.Spend
+		fmt.Fprintf(w, "\t\tSPEND: successful $%.2f, failed $%.2f, retested $%.2f, superseded $%.2f, other $%.2f\n",
+			sp.Successful, sp.Failed, sp.Retested, sp.Superseded, sp.Other)
+		printRetests(w, prInfo)
+	}
+}
+
+// PrintProblems writes the errors of every PR that failed and the number of
+// warnings of the others, and returns how many PRs failed.
+func PrintProblems(w io.Writer, prInfos []cost.PRInfo) int {
+	failed, warned := 0, 0
+	for _, prInfo := range prInfos {
+		if prInfo.Failed() {
+			failed++
+		} else if len(prInfo.Warnings) > 0 {
+			warned++
+		}
+	}
+	if failed == 0 && warned == 0 {
+		return 0
+	}
+
+	fmt.Fprintf(w, "\nProblems: %d of %d PRs failed, %d more have warnings\n", failed, len(prInfos), warned)
+	for _, prInfo := range prInfos {
+		if !prInfo.Failed() && len(prInfo.Warnings) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\t%s/%s#%d: %d errors, %d warnings\n", prInfo.Org, prInfo.Repo, prInfo.PRNum, len(prInfo.Errors), len(prInfo.Warnings))
+		for _, err := range prInfo.Errors {
+			fmt.Fprintf(w, "\t\tERROR: %s\n", err)
+		}
+	}
+	return failed
+}
+
+// PrintRepoSpend writes the spend split of every repo.
+func PrintRepoSpend(w io.Writer, byRepo map[string]cost.Spend) {
+	fmt.Fprintln(w, "\nSpend per repo:")
+	for _, repo := range sortedRepos(byRepo) {
+		sp := byRepo[repo]
+		fmt.Fprintf(w, "\t%s: total $%.2f, wasted $%.2f (failed $%.2f, retested $%.2f, superseded $%.2f), successful $%.2f, other $%.2f\n",
+			repo, sp.Total(), sp.Wasted(), sp.Failed, sp.Retested, sp.Superseded, sp.Successful, sp.Other)
+	}
+}
+
+// PrintStepCosts writes the spend on each multi-stage step across all PRs.
+func PrintStepCosts(w io.Writer, totals []cost.StepTotal) {
+	if len(totals) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\nStep Costs (sorted from most expensive to least):")
+	for _, total := range totals {
+		fmt.Fprintf(w, "\t%-50s RUNS: %5d  HOURS: %8.2f  COSTS: $%.2f\n", total.Name, total.Runs, total.Hours, total.Cost)
+	}
+}
+
+func printRetests(w io.Writer, prInfo cost.PRInfo) {
+	if prInfo.PRRetestCount == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\t\tRETESTS: %d (BOT: %d, HUMAN: %d)\n", prInfo.PRRetestCount, prInfo.BotRetests, prInfo.HumanRetests)
+	for _, author := range sortedKeys(prInfo.RetestsByAuthor) {
+		label := author
+		if prow.IsBot(author) {
+			label += " (bot)"
+		}
+		fmt.Fprintf(w, "\t\t\tBY %s: %d\n", label, prInfo.RetestsByAuthor[author])
+	}
+	for _, job := range sortedKeys(prInfo.RetestsByJob) {
+		fmt.Fprintf(w, "\t\t\tJOB %s: %d\n", job, prInfo.RetestsByJob[job])
+	}
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// PrintPresubmits writes the result counts and pass rate of every job.
+func PrintPresubmits(w io.Writer, jobs []prow.Presubmit) {
+	for _, job := range jobs {
+		fmt.Fprintf(w, "Job name: %s, AlwaysRun: %t, Optional: %t\n", job.Name, job.AlwaysRun, job.Optional)
+		fmt.Fprintf(w, "\t\tSUCCESS count: %d, FAILURE count: %d, ABORTED count: %d, PENDING count: %d, ERROR count: %d, UNKNOWN count: %d\n",
+			job.SuccessCount, job.FailureCount, job.AbortedCount, job.PendingCount, job.ErrorCount, job.UnknownCount)
+		fmt.Fprintf(w, "\t\t\tPASS RATE: %.0f%%\n", job.PassRate*100)
+	}
+}
+
+// PrintStageStats writes the requests, retries, failures and latencies of
+// every pipeline stage.
+func PrintStageStats(w io.Writer, stats []transport.StageStats) {
+	if len(stats) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "Requests per stage:")
+	for _, s := range stats {
+		fmt.Fprintf(w, "\t%-10s %6d requests, %4d retries, %4d failed, latency p50 %s, p95 %s, max %s\n",
+			s.Stage, s.Requests, s.Retries, s.Failures,
+			s.P50.Round(time.Millisecond), s.P95.Round(time.Millisecond), s.Max.Round(time.Millisecond))
+	}
+}
+
+// PrintRepoComparison writes the figures of every repo of a combined report
+// side by side.
+func PrintRepoComparison(w io.Writer, combined *cost.CombinedReport) {
+	fmt.Fprintf(w, "\nRepos compared for %s (%s to %s):\n", combined.Period,
+		combined.Start.Format("2006-01-02"), combined.End.Format("2006-01-02"))
+	fmt.Fprintf(w, "\t%-45s %6s %7s %12s %10s %10s %12s %12s %12s\n",
+		"REPO", "PRS", "FAILED", "TOTAL", "MEAN", "MEDIAN", "RETESTS/PR", "LIFESPAN(d)", "WASTED")
+	for _, s := range combined.Repos {
+		name := s.Org + "/" + s.Repo
+		if s.Partial {
+			name += " (partial)"
+		}
+		fmt.Fprintf(w, "\t%-45s %6d %7d %12s %10s %10s %12.2f %12.1f %12s\n",
+			name, s.PRs, s.FailedPRs, dollars(s.TotalCost), dollars(s.MeanPRCost), dollars(s.MedianPRCost),
+			s.RetestsPerPR, s.MedianLifespan, dollars(s.Spend.Wasted()))
+	}
+}
+
+func dollars(v float64) string {
+	return fmt.Sprintf("$%.2f", v)
+}
+
+// PrintPeriodComparison writes how every repo changed from one period to the
+// next, marking the changes that are significant.
+func PrintPeriodComparison(w io.Writer, comparisonReport *cost.ComparisonReport) {
+	for _, c := range comparisonReport.Repos {
+		fmt.Fprintf(w, "\nPeriods compared for %s/%s:\n", c.Org, c.Repo)
+		for _, p := range c.Periods {
+			partial := ""
+			if p.Partial {
+				partial = " (partial)"
+			}
+			fmt.Fprintf(w, "\t%-25s %s to %s: %d PRs, total %s, mean PR %s, median PR %s, %.2f retests/PR, median lifespan %.1f days%s\n",
+				p.Period, p.Start.Format("2006-01-02"), p.End.Format("2006-01-02"), p.PRs,
+				dollars(p.TotalCost), dollars(p.MeanPRCost), dollars(p.MedianPRCost), p.RetestsPerPR, p.MedianLifespan, partial)
+		}
+		for _, change := range c.Changes {
+			fmt.Fprintf(w, "\t%s -> %s (significant below p=%.2f):\n", change.From, change.To, c.Alpha)
+			for _, m := range change.Metrics {
+				verdict := "not tested, too few PRs"
+				switch {
+				case m.Test == "":
+					verdict = "not tested"
+				case m.Tested:
+					verdict = fmt.Sprintf("p=%.3f (%s)", m.PValue, m.Test)
+					if m.Significant {
+						verdict += " SIGNIFICANT"
+					}
+				}
+				fmt.Fprintf(w, "\t\t%-16s %12.2f -> %12.2f  %+10.2f (%+7.1f%%)  %s\n",
+					m.Metric, m.Before, m.After, m.Change, m.ChangePct, verdict)
+			}
+		}
+	}
+}
+
+// PrintDiff writes how a report differs from an older one.
+func PrintDiff(w io.Writer, diff *cost.Diff) {
+	fmt.Fprintf(w, "Comparing %s to %s\n", diff.Old, diff.New)
+	fmt.Fprintf(w, "\tTOTAL COST: %s -> %s (%+.2f)\n", dollars(diff.OldTotalCost), dollars(diff.NewTotalCost), diff.NewTotalCost-diff.OldTotalCost)
+	if diff.Empty() {
+		fmt.Fprintln(w, "\tNo differences beyond the tolerance")
+		return
+	}
+	for _, pr := range diff.AddedPRs {
+		fmt.Fprintf(w, "\tADDED PR %s\n", pr)
+	}
+	for _, pr := range diff.RemovedPRs {
+		fmt.Fprintf(w, "\tREMOVED PR %s\n", pr)
+	}
+	for _, pr := range diff.Changed {
+		fmt.Fprintf(w, "\tCHANGED PR %s\n", pr.PR)
+		if pr.CostChanged() {
+			fmt.Fprintf(w, "\t\tCOST: %s -> %s (%+.2f)\n", dollars(pr.OldCost), dollars(pr.NewCost), pr.NewCost-pr.OldCost)
+		}
+		if pr.RetestsChanged() {
+			fmt.Fprintf(w, "\t\tRETESTS: %d -> %d\n", pr.OldRetests, pr.NewRetests)
+		}
+		for _, url := range pr.AddedJobs {
+			fmt.Fprintf(w, "\t\tADDED JOB %s\n", url)
+		}
+		for _, url := range pr.RemovedJobs {
+			fmt.Fprintf(w, "\t\tREMOVED JOB %s\n", url)
+		}
+		for _, job := range pr.Jobs {
+			fmt.Fprintf(w, "\t\tJOB %s\n\t\t\tHOURS: %.2f -> %.2f, COST: %s -> %s\n",
+				job.JobURL, job.OldDuration, job.NewDuration, dollars(job.OldCost), dollars(job.NewCost))
+		}
+	}
+	fmt.Fprintf(w, "\n%d PRs added, %d removed, %d changed\n", len(diff.AddedPRs), len(diff.RemovedPRs), len(diff.Changed))
+}
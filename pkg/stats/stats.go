@@ -0,0 +1,171 @@
+// Package stats holds the significance tests used to tell real changes
+// between periods from noise.
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// WelchTTest tests whether the means of a and b differ, without assuming
+// equal variances, and returns the two-sided p-value. ok is false when either
+// sample has fewer than two values.
+func WelchTTest(a, b []float64) (p float64, ok bool) {
+	if len(a) < 2 || len(b) < 2 {
+		return 0, false
+	}
+	meanA, varA := meanVariance(a)
+	meanB, varB := meanVariance(b)
+	seA, seB := varA/float64(len(a)), varB/float64(len(b))
+	if seA+seB == 0 {
+		// both samples are constant
+		if meanA == meanB {
+			return 1, true
+		}
+		return 0, true
+	}
+	t := (meanA - meanB) / math.Sqrt(seA+seB)
+	df := (seA + seB) * (seA + seB) /
+		(seA*seA/float64(len(a)-1) + seB*seB/float64(len(b)-1))
+	return studentTTwoSided(t, df), true
+}
+
+// MannWhitneyU tests whether values of a tend to be larger or smaller than
+// those of b, which is how shifts of medians are tested, and returns the
+// two-sided p-value using the normal approximation with a tie correction. ok
+// is false when either sample is empty.
+func MannWhitneyU(a, b []float64) (p float64, ok bool) {
+	n1, n2 := float64(len(a)), float64(len(b))
+	if n1 == 0 || n2 == 0 {
+		return 0, false
+	}
+
+	type value struct {
+		v     float64
+		fromA bool
+	}
+	values := make([]value, 0, len(a)+len(b))
+	for _, v := range a {
+		values = append(values, value{v, true})
+	}
+	for _, v := range b {
+		values = append(values, value{v, false})
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i].v < values[j].v })
+
+	// rank ties by their average rank
+	rankSumA, tieTerm := 0.0, 0.0
+	for i := 0; i < len(values); {
+		j := i
+		for j < len(values) && values[j].v == values[i].v {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if values[k].fromA {
+				rankSumA += rank
+			}
+		}
+		ties := float64(j - i)
+		tieTerm += ties*ties*ties - ties
+		i = j
+	}
+
+	u := rankSumA - n1*(n1+1)/2
+	n := n1 + n2
+	variance := n1 * n2 / 12 * ((n + 1) - tieTerm/(n*(n-1)))
+	if variance <= 0 {
+		// every value is the same
+		return 1, true
+	}
+	// continuity correction
+	diff := math.Abs(u-n1*n2/2) - 0.5
+	if diff < 0 {
+		diff = 0
+	}
+	z := diff / math.Sqrt(variance)
+	return math.Erfc(z / math.Sqrt2), true
+}
+
+func meanVariance(values []float64) (mean, variance float64) {
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	return mean, variance / float64(len(values)-1)
+}
+
+// studentTTwoSided returns P(|T| >= |t|) for Student's t distribution with df
+// degrees of freedom.
+func studentTTwoSided(t, df float64) float64 {
+	return regularizedBeta(df/(df+t*t), df/2, 0.5)
+}
+
+// regularizedBeta returns the regularized incomplete beta function I_x(a, b),
+// evaluated with a continued fraction.
+func regularizedBeta(x, a, b float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	lga, _ := math.Lgamma(a)
+	lgb, _ := math.Lgamma(b)
+	lgab, _ := math.Lgamma(a + b)
+	front := math.Exp(lgab - lga - lgb + a*math.Log(x) + b*math.Log(1-x))
+	// the continued fraction converges quickly only below this point
+	if x < (a+1)/(a+b+2) {
+		return front * betaFraction(x, a, b) / a
+	}
+	return 1 - front*betaFraction(1-x, b, a)/b
+}
+
+func betaFraction(x, a, b float64) float64 {
+	const (
+		maxIterations = 300
+		epsilon       = 1e-14
+		tiny          = 1e-300
+	)
+	c, d := 1.0, 1-(a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	f := d
+	for m := 1; m <= maxIterations; m++ {
+		fm := float64(m)
+		// even step
+		num := fm * (b - fm) * x / ((a + 2*fm - 1) * (a + 2*fm))
+		d = 1 + num*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + num/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		f *= d * c
+		// odd step
+		num = -(a + fm) * (a + b + fm) * x / ((a + 2*fm) * (a + 2*fm + 1))
+		d = 1 + num*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + num/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		f *= delta
+		if math.Abs(delta-1) < epsilon {
+			break
+		}
+	}
+	return f
+}
@@ -0,0 +1,110 @@
+package stats
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRegularizedBeta(t *testing.T) {
+	tests := []struct {
+		x, a, b, want float64
+	}{
+		// I_x(a, 1) = x^a and I_x(1, b) = 1 - (1-x)^b
+		{0.3, 2.5, 1, math.Pow(0.3, 2.5)},
+		{0.7, 1, 4, 1 - math.Pow(0.3, 4)},
+		// symmetric around 1/2
+		{0.5, 7, 7, 0.5},
+		{0, 2, 3, 0},
+		{1, 2, 3, 1},
+	}
+	for _, tt := range tests {
+		if got := regularizedBeta(tt.x, tt.a, tt.b); math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("regularizedBeta(%v, %v, %v) = %v, want %v", tt.x, tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestStudentTTwoSided(t *testing.T) {
+	tests := []struct {
+		t, df, want, tolerance float64
+	}{
+		// closed forms: Cauchy for one degree of freedom, and
+		// 1 - |t|/sqrt(2+t²) for two
+		{3, 1, 1 - 2/math.Pi*math.Atan(3), 1e-12},
+		{-1.5, 2, 1 - 1.5/math.Sqrt(2+1.5*1.5), 1e-12},
+		{0, 8, 1, 1e-12},
+		// critical values from t tables
+		{2.228, 10, 0.05, 1e-4},
+		{3.169, 10, 0.01, 1e-4},
+		{2.042, 30, 0.05, 1e-4},
+	}
+	for _, tt := range tests {
+		if got := studentTTwoSided(tt.t, tt.df); math.Abs(got-tt.want) > tt.tolerance {
+			t.Errorf("studentTTwoSided(%v, %v) = %v, want %v", tt.t, tt.df, got, tt.want)
+		}
+	}
+}
+
+func TestWelchTTest(t *testing.T) {
+	tests := []struct {
+		name   string
+		a, b   []float64
+		want   float64
+		wantOK bool
+	}{
+		// the examples of Welch's t-test on Wikipedia: t=-2.46, df=25.0
+		// and t=-1.57, df=9.9
+		{
+			name:   "equal sizes",
+			a:      []float64{27.5, 21.0, 19.0, 23.6, 17.0, 17.9, 16.9, 20.1, 21.9, 22.6, 23.1, 19.6, 19.0, 21.7, 21.4},
+			b:      []float64{27.1, 22.0, 20.8, 23.4, 23.4, 23.5, 25.8, 22.0, 24.8, 20.2, 21.9, 22.1, 22.9, 20.5, 24.4},
+			want:   0.021378001,
+			wantOK: true,
+		},
+		{
+			name:   "unequal sizes",
+			a:      []float64{17.2, 20.9, 22.6, 18.1, 21.7, 21.4, 23.5, 24.2, 14.7, 21.8},
+			b:      []float64{21.5, 22.8, 21.0, 23.0, 21.6, 23.6, 22.5, 20.7, 23.4, 21.8, 20.7, 21.7, 21.5, 22.5, 23.6, 21.5, 22.5, 23.5, 21.5, 21.8},
+			want:   0.148841697,
+			wantOK: true,
+		},
+		{name: "unequal variances", a: []float64{1, 2, 3, 4}, b: []float64{10, 20, 30, 40, 50, 60}, want: 0.007917031, wantOK: true},
+		{name: "one value", a: []float64{1}, b: []float64{1, 2, 3}},
+		{name: "empty", a: nil, b: []float64{1, 2}},
+		{name: "constant and equal", a: []float64{2, 2}, b: []float64{2, 2, 2}, want: 1, wantOK: true},
+		{name: "constant and different", a: []float64{2, 2}, b: []float64{3, 3}, want: 0, wantOK: true},
+	}
+	for _, tt := range tests {
+		got, ok := WelchTTest(tt.a, tt.b)
+		if ok != tt.wantOK || math.Abs(got-tt.want) > 1e-8 {
+			t.Errorf("%s: WelchTTest() = %v, %t, want %v, %t", tt.name, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestMannWhitneyU(t *testing.T) {
+	tests := []struct {
+		name   string
+		a, b   []float64
+		want   float64
+		wantOK bool
+	}{
+		// wilcox.test(1:5, 6:10, exact=FALSE) in R
+		{name: "separated", a: []float64{1, 2, 3, 4, 5}, b: []float64{6, 7, 8, 9, 10}, want: 0.012185780, wantOK: true},
+		{name: "ties", a: []float64{1.1, 2.2, 2.2, 3.5, 4.0, 4.0, 6.1}, b: []float64{2.2, 4.0, 5.5, 6.1, 7.0, 8.3}, want: 0.082585039, wantOK: true},
+		// the continuity correction covers the whole difference
+		{name: "centered", a: []float64{3}, b: []float64{1, 2, 4}, want: 1, wantOK: true},
+		{name: "all ties", a: []float64{5, 5, 5}, b: []float64{5, 5}, want: 1, wantOK: true},
+		{name: "empty", a: []float64{1, 2}, b: nil},
+	}
+	for _, tt := range tests {
+		got, ok := MannWhitneyU(tt.a, tt.b)
+		if ok != tt.wantOK || math.Abs(got-tt.want) > 1e-8 {
+			t.Errorf("%s: MannWhitneyU() = %v, %t, want %v, %t", tt.name, got, ok, tt.want, tt.wantOK)
+		}
+		// the test is symmetric
+		if swapped, _ := MannWhitneyU(tt.b, tt.a); math.Abs(swapped-got) > 1e-12 {
+			t.Errorf("%s: MannWhitneyU() with the samples swapped = %v, want %v", tt.name, swapped, got)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+// Package store keeps the results of earlier pr-costs runs on disk, so later
+// runs only fetch PRs that changed and job runs that had not finished.
+//
+// Entries record the measurement settings they were measured with, an opaque
+// string chosen by the caller. An entry measured with other settings is not
+// returned, so it is fetched and measured again.
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"cix/pkg/cost"
+)
+
+// SchemaVersion is the version of the on-disk format. It is bumped whenever
+// stored data can no longer be used as is, such as when a field changes
+// meaning.
+const SchemaVersion = 3
+
+// PR is a processed PR together with the GitHub update time it was
+// processed at.
+type PR struct {
+	UpdatedAt   time.Time
+	Measurement string
+	// the job name patterns whose runs were left out of Info
+	ExcludeJobs []string `json:",omitempty"`
+	Info        cost.PRInfo
+}
+
+// Run is a finished job run together with the measurement settings it was
+// measured with.
+type Run struct {
+	Measurement string
+	Info        cost.JobInfo
+}
+
+// Store holds processed PRs keyed by org/repo#number and finished job runs
+// keyed by their Spyglass URL. It is safe for concurrent use.
+type Store struct {
+	path string
+
+	mu   sync.Mutex
+	prs  map[string]PR
+	runs map[string]Run
+}
+
+// file is the on-disk layout of a Store.
+type file struct {
+	SchemaVersion int
+	PRs           map[string]PR
+	Runs          map[string]Run
+}
+
+// Open loads the store at path, or returns an empty one when the file does not
+// exist yet. With refresh the stored data is ignored and replaced on Save.
+func Open(path string, refresh bool) (*Store, error) {
+	s := &Store{
+		path: path,
+		prs:  make(map[string]PR),
+		runs: make(map[string]Run),
+	}
+	if refresh {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read store: %v", err)
+	}
+
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to decode store %s: %v", path, err)
+	}
+	if f.SchemaVersion != SchemaVersion {
+		return nil, fmt.Errorf("store %s has schema version %d, expected %d; run with -refresh to rebuild it", path, f.SchemaVersion, SchemaVersion)
+	}
+	if f.PRs != nil {
+		s.prs = f.PRs
+	}
+	if f.Runs != nil {
+		s.runs = f.Runs
+	}
+	return s, nil
+}
+
+// Save writes the store back to its file. The new contents are synced to a
+// temporary file that then replaces the store, so a crash or an interrupted
+// save leaves the previous store intact.
+func (s *Store) Save() error {
+	s.mu.Lock()
+	data, err := json.Marshal(file{SchemaVersion: SchemaVersion, PRs: s.prs, Runs: s.runs})
+	s.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to marshal store: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create store: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write store: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write store: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write store: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to replace store: %v", err)
+	}
+	return nil
+}
+
+// PRKey returns the key a PR is stored under.
+func PRKey(org, repo string, prNum int) string {
+	return fmt.Sprintf("%s/%s#%d", org, repo, prNum)
+}
+
+// PR returns the stored PR, if any was measured with the given settings.
+func (s *Store) PR(org, repo string, prNum int, measurement string) (PR, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pr, ok := s.prs[PRKey(org, repo, prNum)]
+	if !ok || pr.Measurement != measurement {
+		return PR{}, false
+	}
+	return pr, true
+}
+
+// PutPR stores a processed PR.
+func (s *Store) PutPR(pr PR) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prs[PRKey(pr.Info.Org, pr.Info.Repo, pr.Info.PRNum)] = pr
+}
+
+// Run returns the stored job run with the given URL, if it was measured with
+// the given settings.
+func (s *Store) Run(url, measurement string) (cost.JobInfo, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	run, ok := s.runs[url]
+	if !ok || run.Measurement != measurement {
+		return cost.JobInfo{}, false
+	}
+	return run.Info, true
+}
+
+// PutRun stores a job run measured with the given settings. Only runs that
+// will not change any more should be stored.
+func (s *Store) PutRun(measurement string, job cost.JobInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.runs[job.JobURL] = Run{Measurement: measurement, Info: job}
+}
+
+// Len returns the number of stored PRs and job runs.
+func (s *Store) Len() (int, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.prs), len(s.runs)
+}
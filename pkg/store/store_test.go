@@ -0,0 +1,100 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"cix/pkg/cost"
+)
+
+func TestStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	s, err := Open(path, false)
+	if err != nil {
+		t.Fatalf("Open() of a missing store failed: %v", err)
+	}
+	pr := PR{
+		UpdatedAt:   time.Date(2023, 7, 3, 10, 0, 0, 0, time.UTC),
+		Measurement: "a",
+		ExcludeJobs: []string{"unit"},
+		Info:        cost.PRInfo{Org: "openshift", Repo: "ovn-kubernetes", PRNum: 1},
+	}
+	job := cost.JobInfo{JobURL: "https://prow.example.com/view/1", JobName: "e2e-aws", Status: cost.StatusComplete, Duration: 1.5}
+	s.PutPR(pr)
+	s.PutRun("a", job)
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	s, err = Open(path, false)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	if prs, runs := s.Len(); prs != 1 || runs != 1 {
+		t.Errorf("Len() = %d, %d, want 1, 1", prs, runs)
+	}
+	if got, ok := s.PR("openshift", "ovn-kubernetes", 1, "a"); !ok || !reflect.DeepEqual(got, pr) {
+		t.Errorf("PR() = %+v, %t, want %+v, true", got, ok, pr)
+	}
+	if got, ok := s.Run(job.JobURL, "a"); !ok || !reflect.DeepEqual(got, job) {
+		t.Errorf("Run() = %+v, %t, want %+v, true", got, ok, job)
+	}
+	// entries measured with other settings are misses
+	if _, ok := s.PR("openshift", "ovn-kubernetes", 1, "b"); ok {
+		t.Errorf("PR() returned a PR measured with other settings")
+	}
+	if _, ok := s.Run(job.JobURL, "b"); ok {
+		t.Errorf("Run() returned a run measured with other settings")
+	}
+
+	s, err = Open(path, true)
+	if err != nil {
+		t.Fatalf("Open() with refresh failed: %v", err)
+	}
+	if prs, runs := s.Len(); prs != 0 || runs != 0 {
+		t.Errorf("refreshed store holds %d PRs and %d runs, want none", prs, runs)
+	}
+}
+
+func TestOpenInvalid(t *testing.T) {
+	tests := map[string]string{
+		"old schema": `{"SchemaVersion": 2}`,
+		"truncated":  `{"SchemaVersion": 3, "PRs": {`,
+	}
+	for name, data := range tests {
+		path := filepath.Join(t.TempDir(), "store.json")
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Open(path, false); err == nil {
+			t.Errorf("%s: Open() succeeded, want an error", name)
+		}
+	}
+}
+
+func TestSaveLeavesNoTemporaryFiles(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(filepath.Join(dir, "store.json"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := s.Save(); err != nil {
+			t.Fatalf("Save() failed: %v", err)
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "store.json" {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Errorf("directory holds %v, want only store.json", names)
+	}
+}
@@ -0,0 +1,331 @@
+// Package transport sends the HTTP requests of the GitHub and Prow clients
+// with per-host concurrency and rate limits, retries server errors and
+// timeouts with exponential backoff, and keeps request and latency statistics
+// per pipeline stage.
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HostLimit bounds the requests sent to one host.
+type HostLimit struct {
+	// requests in flight at once
+	Concurrency int
+	// requests started per second, unlimited when 0
+	Rate float64
+}
+
+// ParseHostLimit parses a limit written as concurrency[:rate], such as 5:10
+// for 5 requests in flight and 10 started per second.
+func ParseHostLimit(s string) (HostLimit, error) {
+	var limit HostLimit
+	concurrency, rate, hasRate := strings.Cut(s, ":")
+	n, err := strconv.Atoi(concurrency)
+	if err != nil || n < 1 {
+		return limit, fmt.Errorf("invalid concurrency %q", concurrency)
+	}
+	limit.Concurrency = n
+	if hasRate {
+		limit.Rate, err = strconv.ParseFloat(rate, 64)
+		if err != nil || limit.Rate < 0 {
+			return limit, fmt.Errorf("invalid rate %q", rate)
+		}
+	}
+	return limit, nil
+}
+
+// Transport is an http.RoundTripper applying host limits and retries.
+type Transport struct {
+	// the transport requests are sent with, http.DefaultTransport when nil
+	Base http.RoundTripper
+	// limits of hosts without one in Hosts
+	Default HostLimit
+	Hosts   map[string]HostLimit
+	// attempts after the first for server errors and timeouts
+	MaxRetries int
+	// wait before the first retry, doubled for every further one
+	Backoff time.Duration
+	// how long a single attempt may take, no limit when 0
+	RequestTimeout time.Duration
+
+	mu     sync.Mutex
+	hosts  map[string]*host
+	stages map[string]*stageStats
+}
+
+type host struct {
+	slots chan struct{}
+	// the earliest time the next request may start
+	mu   sync.Mutex
+	next time.Time
+	gap  time.Duration
+}
+
+type stageStats struct {
+	requests  int
+	failures  int
+	retries   int
+	latencies []time.Duration
+}
+
+// StageStats summarises the requests made by one pipeline stage.
+type StageStats struct {
+	Stage    string
+	Requests int
+	// requests that still failed after retrying
+	Failures int
+	Retries  int
+	P50      time.Duration
+	P95      time.Duration
+	Max      time.Duration
+}
+
+type stageKey struct{}
+
+// WithStage tags the requests made with ctx as belonging to a pipeline stage.
+func WithStage(ctx context.Context, stage string) context.Context {
+	return context.WithValue(ctx, stageKey{}, stage)
+}
+
+func stageOf(req *http.Request) string {
+	if stage, ok := req.Context().Value(stageKey{}).(string); ok {
+		return stage
+	}
+	return req.URL.Host
+}
+
+// Client returns an http.Client that sends its requests through t.
+func (t *Transport) Client() *http.Client {
+	return &http.Client{Transport: t}
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+func (t *Transport) host(name string) *host {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.hosts == nil {
+		t.hosts = make(map[string]*host)
+	}
+	h, ok := t.hosts[name]
+	if !ok {
+		limit, ok := t.Hosts[name]
+		if !ok {
+			limit = t.Default
+		}
+		if limit.Concurrency < 1 {
+			limit.Concurrency = 1
+		}
+		h = &host{slots: make(chan struct{}, limit.Concurrency)}
+		if limit.Rate > 0 {
+			h.gap = time.Duration(float64(time.Second) / limit.Rate)
+		}
+		t.hosts[name] = h
+	}
+	return h
+}
+
+// acquire waits for a free slot and the host's rate limit.
+func (h *host) acquire(ctx context.Context) error {
+	select {
+	case h.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if h.gap == 0 {
+		return nil
+	}
+	h.mu.Lock()
+	now := time.Now()
+	start := h.next
+	if start.Before(now) {
+		start = now
+	}
+	h.next = start.Add(h.gap)
+	h.mu.Unlock()
+	if err := sleep(ctx, time.Until(start)); err != nil {
+		<-h.slots
+		return err
+	}
+	return nil
+}
+
+func (h *host) release() {
+	<-h.slots
+}
+
+// RoundTrip sends req, retrying server errors and timeouts.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	h := t.host(req.URL.Host)
+	stage := stageOf(req)
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			if req.Body != nil && req.GetBody == nil {
+				return nil, fmt.Errorf("cannot retry %s %s: request body is not replayable", req.Method, req.URL)
+			}
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				req = req.Clone(req.Context())
+				req.Body = body
+			}
+		}
+
+		if err := h.acquire(req.Context()); err != nil {
+			return nil, err
+		}
+		start := time.Now()
+		resp, err := t.attempt(req)
+		h.release()
+		latency := time.Since(start)
+
+		retry := attempt < t.MaxRetries && req.Context().Err() == nil && retryable(resp, err)
+		t.record(stage, latency, retry, !retry && (err != nil || resp.StatusCode >= 500))
+		if !retry {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if err := sleep(req.Context(), t.Backoff<<attempt); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// attempt sends req once, bounded by RequestTimeout. The deadline also covers
+// reading the body, and is released when the body is closed.
+func (t *Transport) attempt(req *http.Request) (*http.Response, error) {
+	if t.RequestTimeout <= 0 {
+		return t.base().RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), t.RequestTimeout)
+	resp, err := t.base().RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && req.Context().Err() == nil {
+			return nil, fmt.Errorf("%s %s: request timed out after %s: %w", req.Method, req.URL, t.RequestTimeout, errTimeout)
+		}
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+var errTimeout = errors.New("timeout")
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// retryable reports whether an attempt failed in a way another attempt may
+// fix: a server error, a timeout or a dropped connection.
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		var netErr net.Error
+		return errors.Is(err, errTimeout) || errors.Is(err, io.ErrUnexpectedEOF) ||
+			(errors.As(err, &netErr) && netErr.Timeout()) || errors.Is(err, io.EOF)
+	}
+	return resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented
+}
+
+func (t *Transport) record(stage string, latency time.Duration, retried, failed bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stages == nil {
+		t.stages = make(map[string]*stageStats)
+	}
+	s, ok := t.stages[stage]
+	if !ok {
+		s = &stageStats{}
+		t.stages[stage] = s
+	}
+	s.latencies = append(s.latencies, latency)
+	if retried {
+		s.retries++
+		return
+	}
+	s.requests++
+	if failed {
+		s.failures++
+	}
+}
+
+// Stats returns the statistics of every stage that made requests, sorted by
+// stage name.
+func (t *Transport) Stats() []StageStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var stats []StageStats
+	for stage, s := range t.stages {
+		latencies := append([]time.Duration(nil), s.latencies...)
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		stats = append(stats, StageStats{
+			Stage:    stage,
+			Requests: s.requests,
+			Failures: s.failures,
+			Retries:  s.retries,
+			P50:      percentile(latencies, 0.50),
+			P95:      percentile(latencies, 0.95),
+			Max:      percentile(latencies, 1),
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Stage < stats[j].Stage })
+	return stats
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(p*float64(len(sorted))+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
+
+// sleep waits for d, returning early with the context's error when it is
+// cancelled first.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,308 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyServer answers the first failures requests with status, then 200.
+// It records the body of every request.
+type flakyServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	failures int
+	status   int
+	delay    time.Duration
+	bodies   []string
+}
+
+func newFlakyServer(t *testing.T, failures, status int) *flakyServer {
+	s := &flakyServer{failures: failures, status: status}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		s.bodies = append(s.bodies, string(body))
+		fail := len(s.bodies) <= s.failures
+		delay := s.delay
+		s.mu.Unlock()
+		if fail && delay > 0 {
+			select {
+			case <-time.After(delay):
+			case <-r.Context().Done():
+			}
+			return
+		}
+		if fail {
+			w.WriteHeader(s.status)
+			return
+		}
+		io.WriteString(w, "ok")
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *flakyServer) requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.bodies...)
+}
+
+func TestRoundTripRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int
+		status       int
+		wantStatus   int
+		wantRequests int
+		wantFailures int
+	}{
+		{name: "recovers", failures: 2, status: http.StatusServiceUnavailable, wantStatus: http.StatusOK, wantRequests: 3},
+		{name: "gives up", failures: 10, status: http.StatusInternalServerError, wantStatus: http.StatusInternalServerError, wantRequests: 4, wantFailures: 1},
+		{name: "not implemented", failures: 1, status: http.StatusNotImplemented, wantStatus: http.StatusNotImplemented, wantRequests: 1, wantFailures: 1},
+		{name: "client error", failures: 1, status: http.StatusNotFound, wantStatus: http.StatusNotFound, wantRequests: 1},
+	}
+	for _, tt := range tests {
+		s := newFlakyServer(t, tt.failures, tt.status)
+		tr := &Transport{MaxRetries: 3, Backoff: time.Millisecond}
+		req, err := http.NewRequestWithContext(WithStage(context.Background(), "runs"), http.MethodGet, s.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("%s: RoundTrip() failed: %v", tt.name, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.wantStatus || len(s.requests()) != tt.wantRequests {
+			t.Errorf("%s: got status %d after %d requests, want %d after %d", tt.name, resp.StatusCode, len(s.requests()), tt.wantStatus, tt.wantRequests)
+		}
+		stats := tr.Stats()
+		if len(stats) != 1 || stats[0].Stage != "runs" {
+			t.Fatalf("%s: Stats() = %+v, want the runs stage", tt.name, stats)
+		}
+		if stats[0].Requests != 1 || stats[0].Retries != tt.wantRequests-1 || stats[0].Failures != tt.wantFailures {
+			t.Errorf("%s: Stats() = %+v, want 1 request, %d retries and %d failures", tt.name, stats[0], tt.wantRequests-1, tt.wantFailures)
+		}
+	}
+}
+
+func TestRoundTripReplaysBody(t *testing.T) {
+	s := newFlakyServer(t, 1, http.StatusBadGateway)
+	tr := &Transport{MaxRetries: 1, Backoff: time.Millisecond}
+	req, err := http.NewRequest(http.MethodPost, s.URL, strings.NewReader(`{"query":"q"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip() failed: %v", err)
+	}
+	resp.Body.Close()
+	if bodies := s.requests(); len(bodies) != 2 || bodies[0] != `{"query":"q"}` || bodies[1] != bodies[0] {
+		t.Errorf("server got bodies %q, want the same body twice", bodies)
+	}
+
+	// a body that cannot be replayed is not retried
+	s = newFlakyServer(t, 1, http.StatusBadGateway)
+	req, err = http.NewRequest(http.MethodPost, s.URL, io.NopCloser(bytes.NewReader([]byte("q"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tr.RoundTrip(req); err == nil || !strings.Contains(err.Error(), "not replayable") {
+		t.Errorf("RoundTrip() of a body without GetBody returned %v, want a not replayable error", err)
+	}
+}
+
+func TestRoundTripRequestTimeout(t *testing.T) {
+	s := newFlakyServer(t, 1, 0)
+	s.delay = time.Second
+	tr := &Transport{MaxRetries: 1, Backoff: time.Millisecond, RequestTimeout: 50 * time.Millisecond}
+	req, err := http.NewRequest(http.MethodGet, s.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip() failed: %v", err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || string(body) != "ok" || len(s.requests()) != 2 {
+		t.Errorf("got %q, %v after %d requests, want ok after a timed out request", body, err, len(s.requests()))
+	}
+
+	// without retries the timeout is returned
+	s = newFlakyServer(t, 1, 0)
+	s.delay = time.Second
+	tr.MaxRetries = 0
+	req, err = http.NewRequest(http.MethodGet, s.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tr.RoundTrip(req); !errors.Is(err, errTimeout) {
+		t.Errorf("RoundTrip() returned %v, want a timeout", err)
+	}
+}
+
+// roundTripFunc is an http.RoundTripper calling itself.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestRequestTimeoutReleasedOnClose(t *testing.T) {
+	var attemptCtx context.Context
+	tr := &Transport{
+		RequestTimeout: time.Hour,
+		Base: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			attemptCtx = req.Context()
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("ok"))}, nil
+		}),
+	}
+	req, err := http.NewRequest(http.MethodGet, "http://prow.example.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the deadline covers reading the body
+	if attemptCtx.Err() != nil {
+		t.Fatalf("attempt context done before the body was read: %v", attemptCtx.Err())
+	}
+	resp.Body.Close()
+	if attemptCtx.Err() == nil {
+		t.Errorf("attempt context still live after the body was closed")
+	}
+}
+
+func TestRoundTripCancelledDuringBackoff(t *testing.T) {
+	s := newFlakyServer(t, 10, http.StatusServiceUnavailable)
+	tr := &Transport{MaxRetries: 3, Backoff: time.Hour}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tr.RoundTrip(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("RoundTrip() returned %v, want the context's error", err)
+	}
+}
+
+func TestHostConcurrency(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	tr := &Transport{
+		Default: HostLimit{Concurrency: 10},
+		Hosts:   map[string]HostLimit{"prow.example.com": {Concurrency: 2}},
+		Base: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			mu.Lock()
+			inFlight++
+			if inFlight > maxInFlight {
+				maxInFlight = inFlight
+			}
+			mu.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(""))}, nil
+		}),
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest(http.MethodGet, "http://prow.example.com/", nil)
+			if resp, err := tr.RoundTrip(req); err == nil {
+				resp.Body.Close()
+			}
+		}()
+	}
+	wg.Wait()
+	if maxInFlight != 2 {
+		t.Errorf("%d requests were in flight at once, want 2", maxInFlight)
+	}
+}
+
+func TestHostRate(t *testing.T) {
+	var starts []time.Time
+	tr := &Transport{
+		Hosts: map[string]HostLimit{"api.github.com": {Concurrency: 5, Rate: 50}},
+		Base: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			starts = append(starts, time.Now())
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(""))}, nil
+		}),
+	}
+	for i := 0; i < 4; i++ {
+		req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/", nil)
+		resp, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	// 50 requests per second start 20ms apart
+	for i := 1; i < len(starts); i++ {
+		if gap := starts[i].Sub(starts[i-1]); gap < 19*time.Millisecond {
+			t.Errorf("request %d started %s after the previous one, want at least 20ms", i, gap)
+		}
+	}
+}
+
+func TestParseHostLimit(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    HostLimit
+		wantErr bool
+	}{
+		{in: "5", want: HostLimit{Concurrency: 5}},
+		{in: "5:10", want: HostLimit{Concurrency: 5, Rate: 10}},
+		{in: "1:0.5", want: HostLimit{Concurrency: 1, Rate: 0.5}},
+		{in: "0", wantErr: true},
+		{in: "x", wantErr: true},
+		{in: "5:-1", wantErr: true},
+		{in: "5:fast", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseHostLimit(tt.in)
+		if (err != nil) != tt.wantErr || (!tt.wantErr && got != tt.want) {
+			t.Errorf("ParseHostLimit(%q) = %+v, %v, want %+v, error %t", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := []time.Duration{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	tests := []struct {
+		values []time.Duration
+		p      float64
+		want   time.Duration
+	}{
+		{sorted, 0.5, 5},
+		{sorted, 0.95, 10},
+		{sorted, 1, 10},
+		{sorted, 0, 1},
+		{[]time.Duration{7}, 0.5, 7},
+		{nil, 0.5, 0},
+	}
+	for _, tt := range tests {
+		if got := percentile(tt.values, tt.p); got != tt.want {
+			t.Errorf("percentile(%v, %v) = %v, want %v", tt.values, tt.p, got, tt.want)
+		}
+	}
+}